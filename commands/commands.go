@@ -10,6 +10,7 @@ import (
 	"github.com/docker/machine/commands/mcndirs"
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/crashreport"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnerror"
@@ -92,6 +93,30 @@ func targetHost(c CommandLine, api libmachine.API) (string, error) {
 	return c.Args()[0], nil
 }
 
+// loadCapableHost loads the target machine, and returns drivers.NotImplemented
+// for the operation unless capable accepts its driver. capable usually keeps
+// the driver as the optional interface which the command needs.
+func loadCapableHost(c CommandLine, api libmachine.API, operation string, capable func(drivers.Driver) bool) (*host.Host, error) {
+	target, err := targetHost(c, api)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := api.Load(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if !capable(h.Driver) {
+		return nil, drivers.NotImplemented{
+			DriverName: h.DriverName,
+			Operation:  operation,
+		}
+	}
+
+	return h, nil
+}
+
 func runAction(actionName string, c CommandLine, api libmachine.API) error {
 	var (
 		hostsToLoad []string
@@ -216,6 +241,18 @@ var Commands = []cli.Command{
 		Action:          runCommand(cmdCreateOuter),
 		SkipFlagParsing: true,
 	},
+	{
+		Name:  "driver",
+		Usage: "Show information about a driver",
+		Subcommands: []cli.Command{
+			{
+				Name:        "info",
+				Usage:       "List the regions, zones and other choices offered by the provider of a driver",
+				Description: "Argument is a driver name. The create flags are read from their environment variables.",
+				Action:      runCommand(cmdDriverInfo),
+			},
+		},
+	},
 	{
 		Name:        "env",
 		Usage:       "Display the commands to set up the environment for the Docker client",
@@ -240,6 +277,18 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:  "image",
+		Usage: "Manage the custom images created from machines",
+		Subcommands: []cli.Command{
+			{
+				Name:        "create",
+				Usage:       "Create a custom image from a machine",
+				Description: "Arguments are [machine-name] [image-name].",
+				Action:      runCommand(cmdImageCreate),
+			},
+		},
+	},
 	{
 		Name:        "inspect",
 		Usage:       "Inspect information about a machine",
@@ -265,6 +314,30 @@ var Commands = []cli.Command{
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdKill),
 	},
+	{
+		Name:  "label",
+		Usage: "Manage the labels kept by the driver of a machine",
+		Subcommands: []cli.Command{
+			{
+				Name:        "ls",
+				Usage:       "List the labels of a machine",
+				Description: "Argument is a machine name.",
+				Action:      runCommand(cmdLabelLs),
+			},
+			{
+				Name:        "set",
+				Usage:       "Add or update labels of a machine",
+				Description: "Arguments are [machine-name] [key=value...].",
+				Action:      runCommand(cmdLabelSet),
+			},
+			{
+				Name:        "rm",
+				Usage:       "Remove labels of a machine",
+				Description: "Arguments are [machine-name] [key...].",
+				Action:      runCommand(cmdLabelRm),
+			},
+		},
+	},
 	{
 		Name:   "ls",
 		Usage:  "List machines",
@@ -307,6 +380,22 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:        "resize",
+		Usage:       "Change the size of a machine",
+		Description: "Argument is a machine name.",
+		Action:      runCommand(cmdResize),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "size",
+				Usage: "Driver specific size of the machine, e.g. instance type",
+			},
+			cli.IntFlag{
+				Name:  "bandwidth",
+				Usage: "Maximum outbound bandwidth in Mbps",
+			},
+		},
+	},
 	{
 		Name:        "restart",
 		Usage:       "Restart a machine",
@@ -348,12 +437,53 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:  "snapshot",
+		Usage: "Manage snapshots of the data disk of a machine",
+		Subcommands: []cli.Command{
+			{
+				Name:        "create",
+				Usage:       "Create a snapshot of the data disk of a machine",
+				Description: "Arguments are [machine-name] [snapshot-name].",
+				Action:      runCommand(cmdSnapshotCreate),
+			},
+			{
+				Name:        "ls",
+				Usage:       "List the snapshots of a machine",
+				Description: "Argument is a machine name.",
+				Action:      runCommand(cmdSnapshotLs),
+			},
+			{
+				Name:        "rm",
+				Usage:       "Remove snapshots of a machine",
+				Description: "Arguments are [machine-name] [snapshot-id...].",
+				Action:      runCommand(cmdSnapshotRm),
+			},
+		},
+	},
 	{
 		Name:        "start",
 		Usage:       "Start a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdStart),
 	},
+	{
+		Name:        "stats",
+		Usage:       "Show the resource usage of a machine",
+		Description: "Argument is a machine name.",
+		Action:      runCommand(cmdStats),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "since",
+				Usage: "Show the samples in the duration until now, e.g. 30m or 2h",
+				Value: "1h",
+			},
+			cli.IntFlag{
+				Name:  "period",
+				Usage: "Interval between samples in seconds, the driver default if not specified",
+			},
+		},
+	},
 	{
 		Name:        "status",
 		Usage:       "Get the status of a machine",
@@ -366,6 +496,18 @@ var Commands = []cli.Command{
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdStop),
 	},
+	{
+		Name:        "tunnel",
+		Usage:       "Forward a local port to the Docker daemon of a machine behind an SSH bastion",
+		Description: "Argument is a machine name.",
+		Action:      runCommand(cmdTunnel),
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "port",
+				Usage: "Local port to listen on, a free port from 12376 is allocated to the machine by default",
+			},
+		},
+	},
 	{
 		Name:        "upgrade",
 		Usage:       "Upgrade a machine to the latest version of Docker",
@@ -10,9 +10,12 @@ import (
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/crashreport"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/libmachinetest"
 	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
@@ -235,3 +238,130 @@ func checkErrorCodeForCommand(command func(commandLine CommandLine, api libmachi
 
 	return setExitCode
 }
+
+// fakeCapableDriver implements the optional interfaces of the drivers, it
+// keeps what the commands give and returns what the tests set
+type fakeCapableDriver struct {
+	*fakedriver.Driver
+	snapshots   []drivers.Snapshot
+	resizeOpts  drivers.ResizeOptions
+	labels      map[string]string
+	statsOpts   drivers.StatsOptions
+	samples     []drivers.StatsSample
+	images      []string
+	bastion     string
+	createFlags []mcnflag.Flag
+	driverOpts  drivers.DriverOptions
+	info        []drivers.InfoTable
+}
+
+func newFakeCapableDriver() *fakeCapableDriver {
+	return &fakeCapableDriver{
+		Driver: &fakedriver.Driver{},
+		labels: map[string]string{},
+	}
+}
+
+func (d *fakeCapableDriver) CreateSnapshot(name string) (string, error) {
+	d.snapshots = append(d.snapshots, drivers.Snapshot{ID: "s-" + name, Name: name})
+	return "s-" + name, nil
+}
+
+func (d *fakeCapableDriver) ListSnapshots() ([]drivers.Snapshot, error) {
+	return d.snapshots, nil
+}
+
+func (d *fakeCapableDriver) RemoveSnapshot(id string) error {
+	for i, s := range d.snapshots {
+		if s.ID == id {
+			d.snapshots = append(d.snapshots[:i], d.snapshots[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("snapshot not found")
+}
+
+func (d *fakeCapableDriver) Resize(opts drivers.ResizeOptions) error {
+	d.resizeOpts = opts
+	return nil
+}
+
+func (d *fakeCapableDriver) GetLabels() (map[string]string, error) {
+	return d.labels, nil
+}
+
+func (d *fakeCapableDriver) SetLabels(opts drivers.LabelOptions) error {
+	for k, v := range opts.Set {
+		d.labels[k] = v
+	}
+	for _, k := range opts.Remove {
+		delete(d.labels, k)
+	}
+	return nil
+}
+
+func (d *fakeCapableDriver) GetStats(opts drivers.StatsOptions) ([]drivers.StatsSample, error) {
+	d.statsOpts = opts
+	return d.samples, nil
+}
+
+func (d *fakeCapableDriver) CreateImage(name string) (string, error) {
+	d.images = append(d.images, name)
+	return "m-" + name, nil
+}
+
+func (d *fakeCapableDriver) GetSSHBastion() (string, error) {
+	return d.bastion, nil
+}
+
+func (d *fakeCapableDriver) GetCreateFlags() []mcnflag.Flag {
+	return d.createFlags
+}
+
+func (d *fakeCapableDriver) SetConfigFromFlags(opts drivers.DriverOptions) error {
+	d.driverOpts = opts
+	return nil
+}
+
+func (d *fakeCapableDriver) GetDriverInfo() ([]drivers.InfoTable, error) {
+	return d.info, nil
+}
+
+// newFakeCapableAPI returns the API with the machine default of the driver
+func newFakeCapableAPI(driver drivers.Driver) *libmachinetest.FakeAPI {
+	return &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:       "default",
+				DriverName: "fakedriver",
+				Driver:     driver,
+			},
+		},
+	}
+}
+
+func TestLoadCapableHost(t *testing.T) {
+	isResizer := func(d drivers.Driver) bool {
+		_, ok := d.(drivers.Resizer)
+		return ok
+	}
+
+	h, err := loadCapableHost(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default"},
+	}, newFakeCapableAPI(newFakeCapableDriver()), "resize", isResizer)
+	assert.NoError(t, err)
+	assert.Equal(t, "default", h.Name)
+
+	_, err = loadCapableHost(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default"},
+	}, newFakeCapableAPI(&fakedriver.Driver{}), "resize", isResizer)
+	assert.Equal(t, drivers.NotImplemented{DriverName: "fakedriver", Operation: "resize"}, err)
+
+	_, err = loadCapableHost(&commandstest.FakeCommandLine{
+		CliArgs: []string{"unknown"},
+	}, newFakeCapableAPI(newFakeCapableDriver()), "resize", isResizer)
+	assert.Error(t, err)
+
+	_, err = loadCapableHost(&commandstest.FakeCommandLine{}, &libmachinetest.FakeAPI{}, "resize", isResizer)
+	assert.Equal(t, ErrNoDefault, err)
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/mcnflag"
+)
+
+var (
+	ErrExpectedDriverName = errors.New("Error: Expected one driver name")
+)
+
+// The machine name of the bare driver, no machine is created
+const driverInfoMachineName = "driver-info"
+
+func cmdDriverInfo(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return ErrExpectedDriverName
+	}
+
+	driverName := c.Args().First()
+
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{
+		MachineName: driverInfoMachineName,
+		StorePath:   c.GlobalString("storage-path"),
+	})
+	if err != nil {
+		return fmt.Errorf("Error attempting to marshal bare driver data: %s", err)
+	}
+
+	h, err := api.NewHost(driverName, rawDriver)
+	if err != nil {
+		return err
+	}
+
+	reporter, ok := h.Driver.(drivers.InfoReporter)
+	if !ok {
+		return drivers.NotImplemented{
+			DriverName: driverName,
+			Operation:  "driver info",
+		}
+	}
+
+	driverOpts := getDriverOptsFromEnv(h.Driver.GetCreateFlags())
+	if err := h.Driver.SetConfigFromFlags(driverOpts); err != nil {
+		return fmt.Errorf("Error setting driver configuration from environment: %s", err)
+	}
+
+	tables, err := reporter.GetDriverInfo()
+	if err != nil {
+		return fmt.Errorf("Error getting driver info: %s", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	for i, table := range tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s:\n", table.Title)
+		fmt.Fprintln(w, strings.Join(table.Header, "\t"))
+		for _, row := range table.Rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+	}
+
+	return w.Flush()
+}
+
+// getDriverOptsFromEnv returns the driver options with the default values of
+// the flags, overridden by their environment variables as create does
+func getDriverOptsFromEnv(mcnflags []mcnflag.Flag) drivers.DriverOptions {
+	driverOpts := rpcdriver.RPCFlags{
+		Values: make(map[string]interface{}),
+	}
+
+	for _, f := range mcnflags {
+		driverOpts.Values[f.String()] = f.Default()
+
+		switch f := f.(type) {
+		case mcnflag.StringFlag:
+			if v := os.Getenv(f.EnvVar); f.EnvVar != "" && v != "" {
+				driverOpts.Values[f.Name] = v
+			}
+		case mcnflag.StringSliceFlag:
+			if v := os.Getenv(f.EnvVar); f.EnvVar != "" && v != "" {
+				driverOpts.Values[f.Name] = strings.Split(v, ",")
+			}
+		case mcnflag.IntFlag:
+			if v, err := strconv.Atoi(os.Getenv(f.EnvVar)); f.EnvVar != "" && err == nil {
+				driverOpts.Values[f.Name] = v
+			}
+		case mcnflag.BoolFlag:
+			v, err := strconv.ParseBool(os.Getenv(f.EnvVar))
+			driverOpts.Values[f.Name] = f.EnvVar != "" && err == nil && v
+		}
+	}
+
+	return driverOpts
+}
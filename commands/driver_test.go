@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriverAPI creates the hosts with the given driver
+type fakeDriverAPI struct {
+	*libmachinetest.FakeAPI
+	driver drivers.Driver
+}
+
+func (api *fakeDriverAPI) NewHost(driverName string, rawDriver []byte) (*host.Host, error) {
+	return &host.Host{
+		DriverName: driverName,
+		Driver:     api.driver,
+	}, nil
+}
+
+func TestCmdDriverInfo(t *testing.T) {
+	defer os.Unsetenv("FAKE_REGION")
+	os.Setenv("FAKE_REGION", "moon-1")
+
+	driver := newFakeCapableDriver()
+	driver.createFlags = []mcnflag.Flag{
+		mcnflag.StringFlag{
+			Name:   "fake-region",
+			EnvVar: "FAKE_REGION",
+			Value:  "default",
+		},
+		mcnflag.IntFlag{
+			Name:  "fake-disk-size",
+			Value: 20,
+		},
+	}
+	driver.info = []drivers.InfoTable{
+		{
+			Title:  "Regions",
+			Header: []string{"REGION", "ZONES"},
+			Rows:   [][]string{{"moon-1", "2"}},
+		},
+		{
+			Title:  "Types",
+			Header: []string{"TYPE"},
+			Rows:   [][]string{{"small"}},
+		},
+	}
+	api := &fakeDriverAPI{FakeAPI: &libmachinetest.FakeAPI{}, driver: driver}
+
+	stdoutGetter := commandstest.NewStdoutGetter()
+	defer stdoutGetter.Stop()
+
+	err := cmdDriverInfo(&commandstest.FakeCommandLine{
+		CliArgs:     []string{"fake"},
+		GlobalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{}},
+	}, api)
+	assert.NoError(t, err)
+	assert.Equal(t, "Regions:\n"+
+		"REGION   ZONES\n"+
+		"moon-1   2\n"+
+		"\n"+
+		"Types:\n"+
+		"TYPE\n"+
+		"small\n", stdoutGetter.Output())
+
+	// The flags not set in the environment keep their default values
+	assert.Equal(t, "moon-1", driver.driverOpts.String("fake-region"))
+	assert.Equal(t, 20, driver.driverOpts.Int("fake-disk-size"))
+}
+
+func TestCmdDriverInfoRequiresDriverName(t *testing.T) {
+	err := cmdDriverInfo(&commandstest.FakeCommandLine{}, &libmachinetest.FakeAPI{})
+	assert.Equal(t, ErrExpectedDriverName, err)
+}
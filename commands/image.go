@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	ErrExpectedImageName = errors.New("Error: Expected the machine name and the image name")
+)
+
+func cmdImageCreate(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 2 {
+		c.ShowHelp()
+		return ErrExpectedImageName
+	}
+
+	var creator drivers.ImageCreator
+	h, err := loadCapableHost(c, api, "image", func(d drivers.Driver) (ok bool) {
+		creator, ok = d.(drivers.ImageCreator)
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+
+	name := c.Args()[1]
+	log.Infof("Creating image %s of %s...", name, h.Name)
+
+	id, err := creator.CreateImage(name)
+	if err != nil {
+		return fmt.Errorf("Error creating image: %s", err)
+	}
+
+	fmt.Println(id)
+
+	return api.Save(h)
+}
@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdImageCreate(t *testing.T) {
+	driver := newFakeCapableDriver()
+	api := newFakeCapableAPI(driver)
+
+	err := cmdImageCreate(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default"},
+	}, api)
+	assert.Equal(t, ErrExpectedImageName, err)
+
+	err = cmdImageCreate(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default", "docker-base", "extra"},
+	}, api)
+	assert.Equal(t, ErrExpectedImageName, err)
+
+	stdoutGetter := commandstest.NewStdoutGetter()
+	defer stdoutGetter.Stop()
+
+	err = cmdImageCreate(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default", "docker-base"},
+	}, api)
+	assert.NoError(t, err)
+	assert.Equal(t, "m-docker-base\n", stdoutGetter.Output())
+	assert.Equal(t, []string{"docker-base"}, driver.images)
+}
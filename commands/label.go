@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	ErrExpectedLabels    = errors.New("Error: Expected one or more labels in the form key=value after the machine name")
+	ErrExpectedLabelKeys = errors.New("Error: Expected one or more label keys after the machine name")
+)
+
+func loadLabeler(c CommandLine, api libmachine.API) (*host.Host, drivers.Labeler, error) {
+	var labeler drivers.Labeler
+	h, err := loadCapableHost(c, api, "label", func(d drivers.Driver) (ok bool) {
+		labeler, ok = d.(drivers.Labeler)
+		return ok
+	})
+
+	return h, labeler, err
+}
+
+func cmdLabelLs(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		c.ShowHelp()
+		return ErrExpectedOneMachine
+	}
+
+	_, labeler, err := loadLabeler(c, api)
+	if err != nil {
+		return err
+	}
+
+	labels, err := labeler.GetLabels()
+	if err != nil {
+		return fmt.Errorf("Error listing labels: %s", err)
+	}
+
+	keys := []string{}
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", k, labels[k])
+	}
+
+	return w.Flush()
+}
+
+func cmdLabelSet(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) < 2 {
+		c.ShowHelp()
+		return ErrExpectedLabels
+	}
+
+	labels := map[string]string{}
+	for _, l := range c.Args()[1:] {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("Invalid label %q, expected key=value", l)
+		}
+		labels[kv[0]] = kv[1]
+	}
+
+	return setLabels(c, api, drivers.LabelOptions{Set: labels})
+}
+
+func cmdLabelRm(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) < 2 {
+		c.ShowHelp()
+		return ErrExpectedLabelKeys
+	}
+
+	return setLabels(c, api, drivers.LabelOptions{Remove: c.Args()[1:]})
+}
+
+func setLabels(c CommandLine, api libmachine.API, opts drivers.LabelOptions) error {
+	h, labeler, err := loadLabeler(c, api)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Updating labels of %s...", h.Name)
+
+	if err := labeler.SetLabels(opts); err != nil {
+		return fmt.Errorf("Error updating labels: %s", err)
+	}
+
+	return api.Save(h)
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdLabelSet(t *testing.T) {
+	testCases := []struct {
+		description    string
+		args           []string
+		expectedLabels map[string]string
+		expectedErr    string
+	}{
+		{
+			description:    "no labels",
+			args:           []string{"default"},
+			expectedLabels: map[string]string{"env": "dev"},
+			expectedErr:    ErrExpectedLabels.Error(),
+		},
+		{
+			description:    "no value",
+			args:           []string{"default", "team"},
+			expectedLabels: map[string]string{"env": "dev"},
+			expectedErr:    `Invalid label "team", expected key=value`,
+		},
+		{
+			description:    "no key",
+			args:           []string{"default", "=web"},
+			expectedLabels: map[string]string{"env": "dev"},
+			expectedErr:    `Invalid label "=web", expected key=value`,
+		},
+		{
+			description:    "set and override",
+			args:           []string{"default", "team=web", "env=prod"},
+			expectedLabels: map[string]string{"env": "prod", "team": "web"},
+		},
+		{
+			description:    "value with equal sign and empty value",
+			args:           []string{"default", "query=a=b", "empty="},
+			expectedLabels: map[string]string{"env": "dev", "query": "a=b", "empty": ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		driver := newFakeCapableDriver()
+		driver.labels["env"] = "dev"
+
+		err := cmdLabelSet(&commandstest.FakeCommandLine{
+			CliArgs: tc.args,
+		}, newFakeCapableAPI(driver))
+
+		if tc.expectedErr != "" {
+			assert.EqualError(t, err, tc.expectedErr, tc.description)
+		} else {
+			assert.NoError(t, err, tc.description)
+		}
+		assert.Equal(t, tc.expectedLabels, driver.labels, tc.description)
+	}
+}
+
+func TestCmdLabelRm(t *testing.T) {
+	driver := newFakeCapableDriver()
+	driver.labels = map[string]string{"env": "dev", "team": "web"}
+	api := newFakeCapableAPI(driver)
+
+	err := cmdLabelRm(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default"},
+	}, api)
+	assert.Equal(t, ErrExpectedLabelKeys, err)
+
+	err = cmdLabelRm(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default", "env", "unknown"},
+	}, api)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "web"}, driver.labels)
+}
+
+func TestCmdLabelLs(t *testing.T) {
+	driver := newFakeCapableDriver()
+	driver.labels = map[string]string{"team": "web", "env": "prod"}
+
+	stdoutGetter := commandstest.NewStdoutGetter()
+	defer stdoutGetter.Stop()
+
+	err := cmdLabelLs(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default"},
+	}, newFakeCapableAPI(driver))
+	assert.NoError(t, err)
+	assert.Equal(t, "KEY    VALUE\nenv    prod\nteam   web\n", stdoutGetter.Output())
+}
+
+func TestFilterHostsByDriverLabel(t *testing.T) {
+	opts := FilterOptions{
+		Labels: []string{"env=prod"},
+	}
+	node1 := &host.Host{
+		Name:   "node1",
+		Driver: &fakeCapableDriver{Driver: &fakedriver.Driver{MockState: state.Running}, labels: map[string]string{"env": "prod"}},
+	}
+	node2 := &host.Host{
+		Name:   "node2",
+		Driver: &fakeCapableDriver{Driver: &fakedriver.Driver{MockState: state.Running}, labels: map[string]string{"env": "dev"}},
+	}
+	node3 := &host.Host{
+		Name:   "node3",
+		Driver: &fakedriver.Driver{MockState: state.Running},
+	}
+	// The labels of the machine not running are not looked up
+	node4 := &host.Host{
+		Name:   "node4",
+		Driver: &fakeCapableDriver{Driver: &fakedriver.Driver{MockState: state.Stopped}, labels: map[string]string{"env": "prod"}},
+	}
+
+	actual := filterHosts([]*host.Host{node1, node2, node3, node4}, opts)
+	assert.Equal(t, []*host.Host{node1}, actual)
+}
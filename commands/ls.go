@@ -314,24 +314,61 @@ func matchesLabel(host *host.Host, labels []string) bool {
 		return true
 	}
 
-	var englabels = make(map[string]string, len(host.HostOptions.EngineOptions.Labels))
+	var englabels = make(map[string]string)
 
-	if host.HostOptions != nil && host.HostOptions.EngineOptions.Labels != nil {
+	if host.HostOptions != nil && host.HostOptions.EngineOptions != nil {
 		for _, s := range host.HostOptions.EngineOptions.Labels {
 			kv := strings.SplitN(s, "=", 2)
 			englabels[kv[0]] = kv[1]
 		}
 	}
 
+	if hasLabel(englabels, labels) {
+		return true
+	}
+
+	// The labels kept by driver, e.g. the tags of cloud instance, are matched
+	// as well unless the engine has the same label
+	driverLabels := getDriverLabels(host)
+	for k := range englabels {
+		delete(driverLabels, k)
+	}
+
+	return hasLabel(driverLabels, labels)
+}
+
+func hasLabel(hostLabels map[string]string, labels []string) bool {
 	for _, l := range labels {
 		kv := strings.SplitN(l, "=", 2)
-		if val, exists := englabels[kv[0]]; exists && strings.EqualFold(val, kv[1]) {
+		if val, exists := hostLabels[kv[0]]; exists && strings.EqualFold(val, kv[1]) {
 			return true
 		}
 	}
 	return false
 }
 
+// getDriverLabels returns the labels kept by the driver of a running machine.
+// They are looked up at the provider, so the machines which are stopped or
+// unreachable are skipped to keep ls fast.
+func getDriverLabels(host *host.Host) map[string]string {
+	labeler, ok := host.Driver.(drivers.Labeler)
+	if !ok {
+		return nil
+	}
+
+	if s, err := host.Driver.GetState(); err != nil || s != state.Running {
+		return nil
+	}
+
+	labels, err := labeler.GetLabels()
+	if err != nil {
+		log.Debugf("Unable to get the labels of %s: %s", host.Name, err)
+		return nil
+	}
+
+	return labels
+}
+
 // PERFORMANCE: The code of this function is complicated because we try
 // to call the underlying drivers as less as possible to get the information
 // we need.
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	ErrExpectedResizeOptions = errors.New("Error: Expected --size or --bandwidth to resize the machine")
+)
+
+func cmdResize(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		c.ShowHelp()
+		return ErrExpectedOneMachine
+	}
+
+	opts := drivers.ResizeOptions{
+		Size:      c.String("size"),
+		Bandwidth: c.Int("bandwidth"),
+	}
+	if opts.Size == "" && opts.Bandwidth == 0 {
+		c.ShowHelp()
+		return ErrExpectedResizeOptions
+	}
+
+	var resizer drivers.Resizer
+	h, err := loadCapableHost(c, api, "resize", func(d drivers.Driver) (ok bool) {
+		resizer, ok = d.(drivers.Resizer)
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Resizing %s...", h.Name)
+
+	if err := resizer.Resize(opts); err != nil {
+		return fmt.Errorf("Error resizing machine: %s", err)
+	}
+
+	if err := api.Save(h); err != nil {
+		return err
+	}
+
+	log.Info("Resized machines may have new IP addresses. You may need to re-run the `docker-machine env` command.")
+
+	return nil
+}
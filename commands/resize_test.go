@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdResize(t *testing.T) {
+	testCases := []struct {
+		description  string
+		args         []string
+		flags        map[string]interface{}
+		expectedOpts drivers.ResizeOptions
+		expectedErr  error
+	}{
+		{
+			description: "no options",
+			args:        []string{"default"},
+			flags:       map[string]interface{}{},
+			expectedErr: ErrExpectedResizeOptions,
+		},
+		{
+			description: "too many machines",
+			args:        []string{"default", "other"},
+			flags:       map[string]interface{}{"size": "large"},
+			expectedErr: ErrExpectedOneMachine,
+		},
+		{
+			description:  "size only",
+			args:         []string{"default"},
+			flags:        map[string]interface{}{"size": "large"},
+			expectedOpts: drivers.ResizeOptions{Size: "large"},
+		},
+		{
+			description:  "bandwidth only",
+			args:         []string{"default"},
+			flags:        map[string]interface{}{"bandwidth": 10},
+			expectedOpts: drivers.ResizeOptions{Bandwidth: 10},
+		},
+		{
+			description:  "size and bandwidth",
+			args:         []string{"default"},
+			flags:        map[string]interface{}{"size": "large", "bandwidth": 10},
+			expectedOpts: drivers.ResizeOptions{Size: "large", Bandwidth: 10},
+		},
+	}
+
+	for _, tc := range testCases {
+		driver := newFakeCapableDriver()
+
+		err := cmdResize(&commandstest.FakeCommandLine{
+			CliArgs:    tc.args,
+			LocalFlags: &commandstest.FakeFlagger{Data: tc.flags},
+		}, newFakeCapableAPI(driver))
+
+		assert.Equal(t, tc.expectedErr, err, tc.description)
+		assert.Equal(t, tc.expectedOpts, driver.resizeOpts, tc.description)
+	}
+}
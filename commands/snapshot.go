@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	ErrExpectedSnapshotID = errors.New("Error: Expected one or more snapshot ids after the machine name")
+)
+
+func loadSnapshotter(c CommandLine, api libmachine.API) (*host.Host, drivers.Snapshotter, error) {
+	var snapshotter drivers.Snapshotter
+	h, err := loadCapableHost(c, api, "snapshot", func(d drivers.Driver) (ok bool) {
+		snapshotter, ok = d.(drivers.Snapshotter)
+		return ok
+	})
+
+	return h, snapshotter, err
+}
+
+func cmdSnapshotCreate(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 2 {
+		c.ShowHelp()
+		return ErrTooManyArguments
+	}
+
+	h, snapshotter, err := loadSnapshotter(c, api)
+	if err != nil {
+		return err
+	}
+
+	name := ""
+	if len(c.Args()) == 2 {
+		name = c.Args()[1]
+	}
+
+	log.Infof("Creating snapshot of %s...", h.Name)
+
+	id, err := snapshotter.CreateSnapshot(name)
+	if err != nil {
+		return fmt.Errorf("Error creating snapshot: %s", err)
+	}
+
+	fmt.Println(id)
+
+	return nil
+}
+
+func cmdSnapshotLs(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		c.ShowHelp()
+		return ErrExpectedOneMachine
+	}
+
+	_, snapshotter, err := loadSnapshotter(c, api)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := snapshotter.ListSnapshots()
+	if err != nil {
+		return fmt.Errorf("Error listing snapshots: %s", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSIZE\tPROGRESS\tCREATED")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%dGB\t%s\t%s\n", s.ID, s.Name, s.Size, s.Progress, s.Created)
+	}
+
+	return w.Flush()
+}
+
+func cmdSnapshotRm(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) < 2 {
+		c.ShowHelp()
+		return ErrExpectedSnapshotID
+	}
+
+	_, snapshotter, err := loadSnapshotter(c, api)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, id := range c.Args()[1:] {
+		if err := snapshotter.RemoveSnapshot(id); err != nil {
+			errs = append(errs, fmt.Errorf("Error removing snapshot %q: %s", id, err))
+			continue
+		}
+		log.Infof("Successfully removed snapshot %s", id)
+	}
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdSnapshotCreate(t *testing.T) {
+	driver := newFakeCapableDriver()
+	api := newFakeCapableAPI(driver)
+
+	err := cmdSnapshotCreate(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default", "backup", "extra"},
+	}, api)
+	assert.Equal(t, ErrTooManyArguments, err)
+
+	stdoutGetter := commandstest.NewStdoutGetter()
+	defer stdoutGetter.Stop()
+
+	err = cmdSnapshotCreate(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default", "backup"},
+	}, api)
+	assert.NoError(t, err)
+	assert.Equal(t, "s-backup\n", stdoutGetter.Output())
+	assert.Equal(t, []drivers.Snapshot{{ID: "s-backup", Name: "backup"}}, driver.snapshots)
+}
+
+func TestCmdSnapshotLs(t *testing.T) {
+	driver := newFakeCapableDriver()
+	driver.snapshots = []drivers.Snapshot{
+		{ID: "s-1", Name: "backup", Size: 20, Progress: "100%", Created: "2016-06-01T12:00:00Z"},
+	}
+
+	stdoutGetter := commandstest.NewStdoutGetter()
+	defer stdoutGetter.Stop()
+
+	err := cmdSnapshotLs(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default"},
+	}, newFakeCapableAPI(driver))
+	assert.NoError(t, err)
+	assert.Equal(t, "ID    NAME     SIZE   PROGRESS   CREATED\n"+
+		"s-1   backup   20GB   100%       2016-06-01T12:00:00Z\n", stdoutGetter.Output())
+}
+
+func TestCmdSnapshotRm(t *testing.T) {
+	driver := newFakeCapableDriver()
+	driver.snapshots = []drivers.Snapshot{{ID: "s-1"}, {ID: "s-2"}}
+	api := newFakeCapableAPI(driver)
+
+	err := cmdSnapshotRm(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default"},
+	}, api)
+	assert.Equal(t, ErrExpectedSnapshotID, err)
+
+	// The snapshots are removed even if some of them fail
+	err = cmdSnapshotRm(&commandstest.FakeCommandLine{
+		CliArgs: []string{"default", "s-1", "s-unknown", "s-2"},
+	}, api)
+	assert.EqualError(t, err, `Error removing snapshot "s-unknown": snapshot not found`)
+	assert.Empty(t, driver.snapshots)
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+const defaultStatsSince = time.Hour
+
+// statsNow is replaced in tests to get a fixed time range
+var statsNow = time.Now
+
+func cmdStats(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		c.ShowHelp()
+		return ErrExpectedOneMachine
+	}
+
+	since := defaultStatsSince
+	if s := c.String("since"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("Invalid --since %q, expected a positive duration such as 30m or 2h", s)
+		}
+		since = d
+	}
+
+	period := c.Int("period")
+	if period < 0 {
+		return fmt.Errorf("Invalid --period %d, expected a positive number of seconds", period)
+	}
+
+	var reporter drivers.StatsReporter
+	_, err := loadCapableHost(c, api, "stats", func(d drivers.Driver) (ok bool) {
+		reporter, ok = d.(drivers.StatsReporter)
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+
+	end := statsNow().UTC()
+	samples, err := reporter.GetStats(drivers.StatsOptions{
+		Start:  end.Add(-since),
+		End:    end,
+		Period: period,
+	})
+	if err != nil {
+		return fmt.Errorf("Error getting stats: %s", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "TIME\tCPU\tNET IN\tNET OUT\tDISK READ\tDISK WRITE")
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%s\t%s\t%s\t%s\n",
+			s.Time.Local().Format(time.RFC3339),
+			s.CPU,
+			formatRate(s.NetworkIn),
+			formatRate(s.NetworkOut),
+			formatRate(s.DiskRead),
+			formatRate(s.DiskWrite),
+		)
+	}
+
+	return w.Flush()
+}
+
+func formatRate(bytesPerSecond float64) string {
+	return units.HumanSize(bytesPerSecond) + "/s"
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdStats(t *testing.T) {
+	now := time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { statsNow = f }(statsNow)
+	statsNow = func() time.Time { return now }
+
+	testCases := []struct {
+		description  string
+		flags        map[string]interface{}
+		expectedOpts drivers.StatsOptions
+		expectedErr  string
+	}{
+		{
+			description:  "default range",
+			flags:        map[string]interface{}{},
+			expectedOpts: drivers.StatsOptions{Start: now.Add(-defaultStatsSince), End: now},
+		},
+		{
+			description:  "since and period",
+			flags:        map[string]interface{}{"since": "30m", "period": 300},
+			expectedOpts: drivers.StatsOptions{Start: now.Add(-30 * time.Minute), End: now, Period: 300},
+		},
+		{
+			description: "invalid since",
+			flags:       map[string]interface{}{"since": "yesterday"},
+			expectedErr: `Invalid --since "yesterday", expected a positive duration such as 30m or 2h`,
+		},
+		{
+			description: "negative since",
+			flags:       map[string]interface{}{"since": "-30m"},
+			expectedErr: `Invalid --since "-30m", expected a positive duration such as 30m or 2h`,
+		},
+		{
+			description: "negative period",
+			flags:       map[string]interface{}{"period": -60},
+			expectedErr: "Invalid --period -60, expected a positive number of seconds",
+		},
+	}
+
+	for _, tc := range testCases {
+		driver := newFakeCapableDriver()
+
+		err := cmdStats(&commandstest.FakeCommandLine{
+			CliArgs:    []string{"default"},
+			LocalFlags: &commandstest.FakeFlagger{Data: tc.flags},
+		}, newFakeCapableAPI(driver))
+
+		if tc.expectedErr != "" {
+			assert.EqualError(t, err, tc.expectedErr, tc.description)
+		} else {
+			assert.NoError(t, err, tc.description)
+		}
+		assert.Equal(t, tc.expectedOpts, driver.statsOpts, tc.description)
+	}
+}
+
+func TestCmdStatsOutput(t *testing.T) {
+	sampleTime := time.Date(2016, 6, 1, 11, 0, 0, 0, time.UTC)
+	driver := newFakeCapableDriver()
+	driver.samples = []drivers.StatsSample{
+		{Time: sampleTime, CPU: 12.5, NetworkIn: 1024, NetworkOut: 2048, DiskWrite: 1500},
+	}
+
+	stdoutGetter := commandstest.NewStdoutGetter()
+	defer stdoutGetter.Stop()
+
+	err := cmdStats(&commandstest.FakeCommandLine{
+		CliArgs:    []string{"default"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{}},
+	}, newFakeCapableAPI(driver))
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(stdoutGetter.Output()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, []string{"TIME", "CPU", "NET", "IN", "NET", "OUT", "DISK", "READ", "DISK", "WRITE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{
+		sampleTime.Local().Format(time.RFC3339),
+		"12.5%",
+		"1.024", "kB/s",
+		"2.048", "kB/s",
+		"0", "B/s",
+		"1.5", "kB/s",
+	}, strings.Fields(lines[1]))
+}
+
+func TestFormatRate(t *testing.T) {
+	assert.Equal(t, "1.5 kB/s", formatRate(1500))
+	assert.Equal(t, "0 B/s", formatRate(0))
+}
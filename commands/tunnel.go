@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
+)
+
+var (
+	ErrNoBastion = errors.New("Error: The machine has no SSH bastion, its Docker daemon is reached directly")
+)
+
+func cmdTunnel(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		c.ShowHelp()
+		return ErrExpectedOneMachine
+	}
+
+	target, err := targetHost(c, api)
+	if err != nil {
+		return err
+	}
+
+	h, err := api.Load(target)
+	if err != nil {
+		return err
+	}
+
+	bastion, err := drivers.GetSSHBastion(h.Driver)
+	if err != nil {
+		return err
+	}
+	if bastion == nil {
+		return ErrNoBastion
+	}
+
+	dockerURL, err := h.Driver.GetURL()
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(dockerURL)
+	if err != nil {
+		return fmt.Errorf("Error parsing URL: %s", err)
+	}
+
+	// The port given is kept for the Docker client to be pointed to it
+	if port := c.Int("port"); port != 0 && port != h.TunnelPort {
+		h.TunnelPort = port
+		if err := api.Save(h); err != nil {
+			return err
+		}
+	}
+
+	port, err := allocateTunnelPort(api, h)
+	if err != nil {
+		return err
+	}
+
+	tunnel, err := h.NewBastionTunnel(fmt.Sprintf("localhost:%d", port), u.Host)
+	if err != nil {
+		return fmt.Errorf("Error opening tunnel to %s: %s", u.Host, err)
+	}
+	defer tunnel.Close()
+
+	log.Infof("Forwarding localhost:%d to the Docker daemon of %s at %s, press Ctrl-C to stop.", tunnel.Port(), h.Name, u.Host)
+	log.Info("To point your Docker client to it, run in another shell:")
+	log.Infof("eval $(docker-machine env %s)", h.Name)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	return nil
+}
+
+// allocateTunnelPort returns the local port of the tunnel to the machine. The
+// first port from host.BastionTunnelPort which is not allocated to another
+// machine is allocated on first use, and saved with the machine.
+func allocateTunnelPort(store persist.Store, h *host.Host) (int, error) {
+	if h.TunnelPort != 0 {
+		return h.TunnelPort, nil
+	}
+
+	hosts, _, err := persist.LoadAllHosts(store)
+	if err != nil {
+		return 0, err
+	}
+
+	allocated := map[int]bool{}
+	for _, other := range hosts {
+		if other.Name != h.Name {
+			allocated[other.TunnelPort] = true
+		}
+	}
+
+	port := host.BastionTunnelPort
+	for allocated[port] {
+		port++
+	}
+
+	h.TunnelPort = port
+	if err := store.Save(h); err != nil {
+		return 0, err
+	}
+	return port, nil
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/persist/persisttest"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdTunnelNoBastion(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name: "default",
+				Driver: &fakeCapableDriver{
+					Driver: &fakedriver.Driver{
+						MockState: state.Running,
+						MockIP:    "10.0.0.2",
+					},
+				},
+			},
+		},
+	}
+
+	err := cmdTunnel(&commandstest.FakeCommandLine{
+		CliArgs:    []string{"default"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{}},
+	}, api)
+	assert.Equal(t, ErrNoBastion, err)
+}
+
+func TestCmdTunnelInvalidBastion(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name: "default",
+				Driver: &fakeCapableDriver{
+					Driver: &fakedriver.Driver{
+						MockState: state.Running,
+						MockIP:    "10.0.0.2",
+					},
+					bastion: "jump.example.com",
+				},
+			},
+		},
+	}
+
+	err := cmdTunnel(&commandstest.FakeCommandLine{
+		CliArgs:    []string{"default"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{}},
+	}, api)
+	assert.Error(t, err)
+}
+
+func TestAllocateTunnelPort(t *testing.T) {
+	node1 := &host.Host{Name: "node1", TunnelPort: host.BastionTunnelPort}
+	node2 := &host.Host{Name: "node2", TunnelPort: host.BastionTunnelPort + 2}
+	node3 := &host.Host{Name: "node3"}
+	node4 := &host.Host{Name: "node4"}
+	store := &persisttest.FakeStore{
+		Hosts: []*host.Host{node1, node2, node3, node4},
+	}
+
+	port, err := allocateTunnelPort(store, node3)
+	assert.NoError(t, err)
+	assert.Equal(t, host.BastionTunnelPort+1, port)
+	assert.Equal(t, port, node3.TunnelPort)
+
+	port, err = allocateTunnelPort(store, node4)
+	assert.NoError(t, err)
+	assert.Equal(t, host.BastionTunnelPort+3, port)
+
+	// The allocated port is kept
+	port, err = allocateTunnelPort(store, node2)
+	assert.NoError(t, err)
+	assert.Equal(t, host.BastionTunnelPort+2, port)
+}
@@ -0,0 +1,130 @@
+package aliyunecs
+
+import (
+	"fmt"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/ssh"
+)
+
+func (d *Driver) validateAdoption() error {
+	if d.SSHPassword == "" && !d.ExistingKeyPair {
+		return fmt.Errorf("%s | The --aliyunecs-ssh-password or --aliyunecs-keypair-name of instance is required to adopt instance %s", d.MachineName, d.InstanceId)
+	}
+	if d.CreateVPC || d.VpcId != "" {
+		return fmt.Errorf("%s | The VPC of adopted instance %s can not be specified", d.MachineName, d.InstanceId)
+	}
+	if d.hasDataDisk() {
+		return fmt.Errorf("%s | The data disk of adopted instance %s can not be specified", d.MachineName, d.InstanceId)
+	}
+	if d.ExistingEip {
+		return fmt.Errorf("%s | The EIP of adopted instance %s can not be specified", d.MachineName, d.InstanceId)
+	}
+	if d.DeploymentSetId != "" || d.SwarmDeploymentSet {
+		return fmt.Errorf("%s | The deployment set of adopted instance %s can not be specified", d.MachineName, d.InstanceId)
+	}
+	return nil
+}
+
+// adoptInstance takes over an existing instance instead of creating one
+func (d *Driver) adoptInstance() error {
+	log.Infof("%s | Adopting instance %s ...", d.MachineName, d.InstanceId)
+
+	instance, err := d.describeInstanceAttribute(d.InstanceId)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe instance %s: %v", d.MachineName, d.InstanceId, err)
+	}
+
+	d.Region = instance.RegionId
+	d.Zone = instance.ZoneId
+	d.ImageID = instance.ImageId
+	d.InstanceType = instance.InstanceType
+	d.VpcId = instance.VpcAttributes.VpcId
+	d.VSwitchId = instance.VpcAttributes.VSwitchId
+	d.InternetChargeType = instance.InternetChargeType
+	d.InternetMaxBandwidthOut = instance.InternetMaxBandwidthOut
+	d.DeploymentSetId = instance.DeploymentSetId
+	if instance.InstanceChargeType != "" {
+		d.InstanceChargeType = instance.InstanceChargeType
+	}
+
+	// The security group of adopted instance is possibly managed by others,
+	// its rules are left as they are
+	if len(instance.SecurityGroupIds.SecurityGroupId) > 0 {
+		securityGroup, err := d.getSecurityGroup(instance.SecurityGroupIds.SecurityGroupId[0])
+		if err != nil {
+			return fmt.Errorf("%s | Failed to describe security group of instance %s: %v", d.MachineName, d.InstanceId, err)
+		}
+		d.SecurityGroupId = securityGroup.SecurityGroupId
+		d.SecurityGroupName = securityGroup.SecurityGroupName
+		d.ExistingSecurityGroup = true
+		log.Infof("%s | Using security group %s of instance without changing its rules", d.MachineName, securityGroup.SecurityGroupId)
+	}
+
+	if d.RamRoleName != "" {
+		log.Infof("%s | Attaching RAM role %s to instance %s ...", d.MachineName, d.RamRoleName, d.InstanceId)
+		if err := d.attachInstanceRamRole(d.InstanceId, d.RamRoleName); err != nil {
+			return fmt.Errorf("%s | Failed to attach RAM role %s to instance %s: %v", d.MachineName, d.RamRoleName, d.InstanceId, err)
+		}
+	}
+
+	switch instance.Status {
+	case ecs.Running:
+	case ecs.Stopped:
+		log.Infof("%s | Starting instance %s ...", d.MachineName, d.InstanceId)
+		if err := d.Start(); err != nil {
+			return err
+		}
+	default:
+		client, err := d.getClient()
+		if err != nil {
+			return err
+		}
+		if err := client.WaitForInstance(d.InstanceId, ecs.Running, timeout); err != nil {
+			return fmt.Errorf("%s | Failed to wait instance %s running: %v", d.MachineName, d.InstanceId, err)
+		}
+	}
+
+	inst, err := d.getInstance()
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe instance %s: %v", d.MachineName, d.InstanceId, err)
+	}
+	d.PrivateIPAddress = d.GetPrivateIP(inst)
+	d.IPAddress = d.getIP(inst)
+	// The EIP of adopted instance is kept on removal
+	if inst.EipAddress.AllocationId != "" {
+		d.EipId = inst.EipAddress.AllocationId
+		d.EipAddress = inst.EipAddress.IpAddress
+		d.ExistingEip = true
+	}
+
+	if d.IPAddress == "" {
+		return fmt.Errorf("%s | No IP address found for instance %s", d.MachineName, d.InstanceId)
+	}
+
+	ssh.SetDefaultClient(ssh.Native)
+	if err := d.setupInstance(); err != nil {
+		return fmt.Errorf("%s | Failed to set up instance %s: %v", d.MachineName, d.InstanceId, err)
+	}
+
+	// Only the instance is tagged, the disks and EIP of adopted instance are
+	// left as they were
+	if len(d.Tags) > 0 {
+		log.Infof("%s | Adding tags %v to instance %s ...", d.MachineName, d.Tags, d.InstanceId)
+		if err := d.addResourceTags(ecs.TagResourceInstance, d.InstanceId, d.Tags); err != nil {
+			log.Warnf("%s | Failed to add tags to instance %s: %v", d.MachineName, d.InstanceId, err)
+		}
+	}
+	if labels, err := d.GetLabels(); err == nil {
+		d.Tags = labels
+	}
+
+	log.Infof("%s | Adopted instance %s successfully with public IP address %s and private IP address %s",
+		d.MachineName,
+		d.InstanceId,
+		d.IPAddress,
+		d.PrivateIPAddress,
+	)
+	return nil
+}
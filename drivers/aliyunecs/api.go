@@ -0,0 +1,465 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+)
+
+// The ECS APIs and parameters which are not covered by the vendored aliyungo
+
+type InstanceChargeType string
+
+const (
+	PostPaid = InstanceChargeType("PostPaid")
+	PrePaid  = InstanceChargeType("PrePaid")
+)
+
+type createInstanceArgs struct {
+	ecs.CreateInstanceArgs
+	InstanceChargeType InstanceChargeType
+	Period             int
+	AutoRenew          *bool  //optional
+	UserData           string //Base64 encoded
+	SpotStrategy       SpotStrategy
+	SpotPriceLimit     *float64 //optional
+	RamRoleName        string
+	KeyPairName        string
+	DeploymentSetId    string
+}
+
+func (d *Driver) createInstance(args *createInstanceArgs) (instanceId string, err error) {
+	response := ecs.CreateInstanceResponse{}
+	err = d.invoke("CreateInstance", args, &response)
+	if err != nil {
+		return "", err
+	}
+	return response.InstanceId, nil
+}
+
+type modifyInstanceAutoRenewAttributeArgs struct {
+	InstanceId string
+	AutoRenew  bool
+}
+
+// modifyInstanceAutoRenewAttribute turns on/off the auto renewal of PrePaid instance
+func (d *Driver) modifyInstanceAutoRenewAttribute(instanceId string, autoRenew bool) error {
+	args := modifyInstanceAutoRenewAttributeArgs{
+		InstanceId: instanceId,
+		AutoRenew:  autoRenew,
+	}
+	response := common.Response{}
+	return d.invoke("ModifyInstanceAutoRenewAttribute", &args, &response)
+}
+
+type operationLocks struct {
+	LockReason []struct {
+		LockReason string
+	}
+}
+
+type instanceAttributes struct {
+	ecs.InstanceAttributesType
+	InstanceChargeType InstanceChargeType
+	ExpiredTime        string
+	SpotStrategy       SpotStrategy
+	OperationLocks     operationLocks
+	DeploymentSetId    string
+}
+
+type describeInstanceAttributeResponse struct {
+	common.Response
+	instanceAttributes
+}
+
+// describeInstanceAttribute describes the instance with its charge type
+func (d *Driver) describeInstanceAttribute(instanceId string) (*instanceAttributes, error) {
+	args := ecs.DescribeInstanceAttributeArgs{InstanceId: instanceId}
+	response := describeInstanceAttributeResponse{}
+	err := d.invoke("DescribeInstanceAttribute", &args, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.instanceAttributes, nil
+}
+
+type revokeSecurityGroupArgs struct {
+	SecurityGroupId string
+	RegionId        common.Region
+	IpProtocol      ecs.IpProtocol
+	PortRange       string
+	SourceCidrIp    string
+	Policy          ecs.PermissionPolicy
+	NicType         ecs.NicType
+}
+
+// revokeSecurityGroup removes the ingress permission from security group
+func (d *Driver) revokeSecurityGroup(securityGroupId string, p ecs.PermissionType) error {
+	args := revokeSecurityGroupArgs{
+		SecurityGroupId: securityGroupId,
+		RegionId:        d.Region,
+		IpProtocol:      p.IpProtocol,
+		PortRange:       p.PortRange,
+		SourceCidrIp:    p.SourceCidrIp,
+		Policy:          p.Policy,
+		NicType:         p.NicType,
+	}
+	response := common.Response{}
+	return d.invoke("RevokeSecurityGroup", &args, &response)
+}
+
+type describePriceArgs struct {
+	RegionId     common.Region
+	ResourceType string
+	InstanceType string
+	IoOptimized  ecs.IoOptimized
+}
+
+type describePriceResponse struct {
+	common.Response
+	PriceInfo struct {
+		Price struct {
+			OriginalPrice float64
+			DiscountPrice float64
+			TradePrice    float64
+			Currency      string
+		}
+	}
+}
+
+// describeInstancePrice returns the hourly price of instance type
+func (d *Driver) describeInstancePrice(instanceType string, ioOptimized ecs.IoOptimized) (float64, error) {
+	args := describePriceArgs{
+		RegionId:     d.Region,
+		ResourceType: "instance",
+		InstanceType: instanceType,
+		IoOptimized:  ioOptimized,
+	}
+	response := describePriceResponse{}
+	err := d.invoke("DescribePrice", &args, &response)
+	if err != nil {
+		return 0, err
+	}
+	return response.PriceInfo.Price.TradePrice, nil
+}
+
+type modifyInstanceSpecArgs struct {
+	InstanceId              string
+	InstanceType            string
+	InternetMaxBandwidthOut int
+}
+
+// modifyInstanceSpec changes the instance type or the Internet bandwidth of
+// PostPaid instance, the instance must be stopped to change its type
+func (d *Driver) modifyInstanceSpec(args *modifyInstanceSpecArgs) error {
+	response := common.Response{}
+	return d.invoke("ModifyInstanceSpec", args, &response)
+}
+
+// modifyDiskAttribute works around the vendored ModifyDiskAttribute which
+// passes the pointer of args to Invoke
+func (d *Driver) modifyDiskAttribute(args *ecs.ModifyDiskAttributeArgs) error {
+	response := common.Response{}
+	return d.invoke("ModifyDiskAttribute", args, &response)
+}
+
+// The tags of EIP are managed by the VPC API
+const (
+	vpcAPIEndpoint = "https://vpc.aliyuncs.com"
+	vpcAPIVersion  = "2016-04-28"
+)
+
+const tagResourceSecurityGroup = ecs.TagResourceType("securitygroup")
+
+// newAPIClient returns the client of the Aliyun API other than ECS and SLB,
+// the custom API endpoint is used for all APIs if specified
+func (d *Driver) newAPIClient(endpoint string, version string) (*common.Client, error) {
+	creds, err := d.credentials()
+	if err != nil {
+		return nil, err
+	}
+	if d.APIEndpoint != "" {
+		endpoint = d.APIEndpoint
+	}
+	client := &common.Client{}
+	client.Init(endpoint, version, creds.AccessKeyId, creds.AccessKeySecret)
+	client.SetSecurityToken(creds.SecurityToken)
+	return client, nil
+}
+
+func (d *Driver) getVPCClient() (*common.Client, error) {
+	if d.vpcClient == nil {
+		client, err := d.newAPIClient(vpcAPIEndpoint, vpcAPIVersion)
+		if err != nil {
+			return nil, err
+		}
+		d.vpcClient = client
+	}
+	return d.vpcClient, nil
+}
+
+// tagEip adds the tags to EIP with TagResources of VPC API
+func (d *Driver) tagEip(allocationId string, tags map[string]string) error {
+	args := url.Values{}
+	args.Set("RegionId", string(d.Region))
+	args.Set("ResourceType", "EIP")
+	args.Set("ResourceId.1", allocationId)
+	i := 0
+	for _, k := range sortedTagKeys(tags) {
+		i++
+		args.Set(fmt.Sprintf("Tag.%d.Key", i), k)
+		args.Set(fmt.Sprintf("Tag.%d.Value", i), tags[k])
+	}
+	client, err := d.getVPCClient()
+	if err != nil {
+		return err
+	}
+	response := common.Response{}
+	return client.Invoke("TagResources", args, &response)
+}
+
+// untagEip removes the tags from EIP with UntagResources of VPC API
+func (d *Driver) untagEip(allocationId string, tags map[string]string) error {
+	args := url.Values{}
+	args.Set("RegionId", string(d.Region))
+	args.Set("ResourceType", "EIP")
+	args.Set("ResourceId.1", allocationId)
+	i := 0
+	for _, k := range sortedTagKeys(tags) {
+		i++
+		args.Set(fmt.Sprintf("TagKey.%d", i), k)
+	}
+	client, err := d.getVPCClient()
+	if err != nil {
+		return err
+	}
+	response := common.Response{}
+	return client.Invoke("UntagResources", args, &response)
+}
+
+// The RAM roles are managed by the RAM API
+const (
+	ramAPIEndpoint = "https://ram.aliyuncs.com"
+	ramAPIVersion  = "2015-05-01"
+)
+
+func (d *Driver) getRAMClient() (*common.Client, error) {
+	if d.ramClient == nil {
+		client, err := d.newAPIClient(ramAPIEndpoint, ramAPIVersion)
+		if err != nil {
+			return nil, err
+		}
+		d.ramClient = client
+	}
+	return d.ramClient, nil
+}
+
+type ramRole struct {
+	RoleId                   string
+	RoleName                 string
+	Arn                      string
+	AssumeRolePolicyDocument string
+}
+
+type getRoleArgs struct {
+	RoleName string
+}
+
+type getRoleResponse struct {
+	common.Response
+	Role ramRole
+}
+
+// getRole describes the RAM role with GetRole of RAM API
+func (d *Driver) getRole(roleName string) (*ramRole, error) {
+	client, err := d.getRAMClient()
+	if err != nil {
+		return nil, err
+	}
+	args := getRoleArgs{RoleName: roleName}
+	response := getRoleResponse{}
+	err = client.Invoke("GetRole", &args, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.Role, nil
+}
+
+type attachInstanceRamRoleArgs struct {
+	RegionId    common.Region
+	RamRoleName string
+	InstanceIds []string //JSON array
+}
+
+// attachInstanceRamRole attaches the RAM role to the existing instance
+func (d *Driver) attachInstanceRamRole(instanceId string, roleName string) error {
+	args := attachInstanceRamRoleArgs{
+		RegionId:    d.Region,
+		RamRoleName: roleName,
+		InstanceIds: []string{instanceId},
+	}
+	response := common.Response{}
+	return d.invoke("AttachInstanceRamRole", &args, &response)
+}
+
+type keyPair struct {
+	KeyPairName        string
+	KeyPairFingerPrint string
+}
+
+type importKeyPairArgs struct {
+	RegionId      common.Region
+	KeyPairName   string
+	PublicKeyBody string
+}
+
+type importKeyPairResponse struct {
+	common.Response
+	keyPair
+}
+
+// importKeyPair imports the public key as the key pair to launch instance with
+func (d *Driver) importKeyPair(name string, publicKey []byte) (*keyPair, error) {
+	args := importKeyPairArgs{
+		RegionId:      d.Region,
+		KeyPairName:   name,
+		PublicKeyBody: string(publicKey),
+	}
+	response := importKeyPairResponse{}
+	err := d.invoke("ImportKeyPair", &args, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.keyPair, nil
+}
+
+type describeKeyPairsArgs struct {
+	RegionId    common.Region
+	KeyPairName string
+}
+
+type describeKeyPairsResponse struct {
+	common.Response
+	KeyPairs struct {
+		KeyPair []keyPair
+	}
+}
+
+// describeKeyPair returns the key pair with the name, or nil if it doesn't exist
+func (d *Driver) describeKeyPair(name string) (*keyPair, error) {
+	args := describeKeyPairsArgs{
+		RegionId:    d.Region,
+		KeyPairName: name,
+	}
+	response := describeKeyPairsResponse{}
+	err := d.invoke("DescribeKeyPairs", &args, &response)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range response.KeyPairs.KeyPair {
+		if k.KeyPairName == name {
+			return &k, nil
+		}
+	}
+	return nil, nil
+}
+
+type deleteKeyPairsArgs struct {
+	RegionId     common.Region
+	KeyPairNames []string //JSON array
+}
+
+func (d *Driver) deleteKeyPairs(names ...string) error {
+	args := deleteKeyPairsArgs{
+		RegionId:     d.Region,
+		KeyPairNames: names,
+	}
+	response := common.Response{}
+	return d.invoke("DeleteKeyPairs", &args, &response)
+}
+
+// createImageArgs adds the InstanceId missing from the vendored
+// CreateImageArgs to create image from the system disk of instance
+type createImageArgs struct {
+	RegionId    common.Region
+	InstanceId  string
+	ImageName   string
+	Description string
+	ClientToken string
+}
+
+func (d *Driver) createImage(args *createImageArgs) (string, error) {
+	response := ecs.CreateImageResponse{}
+	err := d.invoke("CreateImage", args, &response)
+	if err != nil {
+		return "", err
+	}
+	return response.ImageId, nil
+}
+
+type deploymentSet struct {
+	DeploymentSetId   string
+	DeploymentSetName string
+	Strategy          string
+	InstanceAmount    int
+}
+
+type describeDeploymentSetsArgs struct {
+	RegionId          common.Region
+	DeploymentSetIds  []string //JSON array
+	DeploymentSetName string
+}
+
+type describeDeploymentSetsResponse struct {
+	common.Response
+	DeploymentSets struct {
+		DeploymentSet []deploymentSet
+	}
+}
+
+func (d *Driver) describeDeploymentSets(args *describeDeploymentSetsArgs) ([]deploymentSet, error) {
+	response := describeDeploymentSetsResponse{}
+	err := d.invoke("DescribeDeploymentSets", args, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.DeploymentSets.DeploymentSet, nil
+}
+
+type createDeploymentSetArgs struct {
+	RegionId          common.Region
+	DeploymentSetName string
+	Description       string
+	Strategy          string
+	ClientToken       string
+}
+
+type createDeploymentSetResponse struct {
+	common.Response
+	DeploymentSetId string
+}
+
+func (d *Driver) createDeploymentSet(args *createDeploymentSetArgs) (string, error) {
+	response := createDeploymentSetResponse{}
+	err := d.invoke("CreateDeploymentSet", args, &response)
+	if err != nil {
+		return "", err
+	}
+	return response.DeploymentSetId, nil
+}
+
+type deleteDeploymentSetArgs struct {
+	RegionId        common.Region
+	DeploymentSetId string
+}
+
+func (d *Driver) deleteDeploymentSet(deploymentSetId string) error {
+	args := deleteDeploymentSetArgs{
+		RegionId:        d.Region,
+		DeploymentSetId: deploymentSetId,
+	}
+	response := common.Response{}
+	return d.invoke("DeleteDeploymentSet", &args, &response)
+}
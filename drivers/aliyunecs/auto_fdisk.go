@@ -1,33 +1,48 @@
 package aliyunecs
 
-const autoFdiskScript = `#/bin/bash
-#fdisk ,formating and create the file system on /dev/xvdb
-fdisk_fun()
+// autoFdiskScript partitions, formats and mounts the data disks, it is safe to
+// run again as the disk with file system is never formatted again. The disk is
+// /dev/xvd? on the Xen instance, and /dev/vd? with virtio on I/O optimized one.
+const autoFdiskScript = `#!/bin/bash
+#mount_disk <device letter> <mount point> <file system>
+mount_disk()
 {
-fdisk -S 56 /dev/xvdb << EOF
-n
-p
-1
-
-
-wq
-EOF
-
-sleep 5
-mkfs.ext4 /dev/xvdb1
-}
-
-#config /etc/fstab and mount device
-main()
-{
-  fdisk_fun
-  mkdir /var/lib/docker
-  echo "/dev/xvdb1    /var/lib/docker     ext4    defaults        0 0" >>/etc/fstab
-  mount -a
+  DEVICE=/dev/xvd$1
+  if [ ! -b $DEVICE ]; then
+    DEVICE=/dev/vd$1
+  fi
+  if [ ! -b $DEVICE ]; then
+    echo "No data disk /dev/xvd$1 or /dev/vd$1" >&2
+    return 1
+  fi
+
+  if mountpoint -q $2; then
+    echo "$2 is mounted already"
+    return 0
+  fi
+
+  PARTITION=${DEVICE}1
+  if [ -n "$(blkid -o value -s TYPE $DEVICE)" ]; then
+    # The whole disk is formatted without partition
+    PARTITION=$DEVICE
+  elif [ ! -b $PARTITION ]; then
+    printf "n\np\n1\n\n\nw\n" | fdisk $DEVICE
+    partprobe $DEVICE 2>/dev/null || sleep 5
+  fi
+
+  TYPE=$(blkid -o value -s TYPE $PARTITION)
+  if [ -z "$TYPE" ]; then
+    mkfs -t $3 $PARTITION || return 1
+    TYPE=$3
+  elif [ "$TYPE" != "$3" ]; then
+    echo "$PARTITION is formatted as $TYPE already, it is not wiped to $3" >&2
+  fi
+
+  mkdir -p $2
+  if ! grep -q "^$PARTITION " /etc/fstab; then
+    echo "$PARTITION    $2    $TYPE    defaults    0 0" >>/etc/fstab
+  fi
+  mount $2
 }
 
-main
-df -h
-
-
 `
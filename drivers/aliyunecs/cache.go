@@ -0,0 +1,74 @@
+package aliyunecs
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// The time the catalogues of regions, zones and instance types are cached
+var catalogueCacheTTL = 24 * time.Hour
+
+type catalogueCache struct {
+	Time time.Time
+	Data json.RawMessage
+}
+
+// cachePath returns the path of the cache file in the store directory, the
+// catalogues of a custom API endpoint are cached separately
+func (d *Driver) cachePath(name string) string {
+	if d.APIEndpoint != "" {
+		name = fmt.Sprintf("%x-%s", sha1.Sum([]byte(d.APIEndpoint)), name)
+	}
+	return filepath.Join(d.StorePath, "cache", driverName, name)
+}
+
+// readCache decodes the cached catalogue into v, it returns false if the
+// cache is missing or expired
+func (d *Driver) readCache(name string, v interface{}) bool {
+	if d.StorePath == "" {
+		return false
+	}
+
+	data, err := ioutil.ReadFile(d.cachePath(name))
+	if err != nil {
+		return false
+	}
+	cache := catalogueCache{}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Debugf("%s | Ignoring invalid cache %s: %v", d.MachineName, name, err)
+		return false
+	}
+	if time.Since(cache.Time) > catalogueCacheTTL {
+		return false
+	}
+	return json.Unmarshal(cache.Data, v) == nil
+}
+
+// writeCache caches the catalogue, the failure is ignored as the catalogue is
+// fetched from the API again
+func (d *Driver) writeCache(name string, v interface{}) {
+	if d.StorePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err == nil {
+		data, err = json.Marshal(catalogueCache{Time: time.Now(), Data: data})
+	}
+	if err == nil {
+		path := d.cachePath(name)
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+			err = ioutil.WriteFile(path, data, 0600)
+		}
+	}
+	if err != nil {
+		log.Debugf("%s | Failed to write cache %s: %v", d.MachineName, name, err)
+	}
+}
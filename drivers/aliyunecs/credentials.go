@@ -0,0 +1,160 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/go-ini/ini"
+)
+
+const (
+	defaultCredentialsProfile = "default"
+	securityTokenEnvVar       = "ECS_SECURITY_TOKEN"
+	errorMissingCredentials   = "aliyunecs driver requires the --aliyunecs-access-key-id and --aliyunecs-access-key-secret options, the ALIYUN_ACCESS_KEY_ID and ALIYUN_ACCESS_KEY_SECRET environment variables or proper credentials in ~/.aliyun/credentials"
+)
+
+// credentials is the access key of Aliyun account or RAM user, with the STS
+// security token if the access key is temporary
+type credentials struct {
+	AccessKeyId     string
+	AccessKeySecret string
+	SecurityToken   string
+}
+
+func (c credentials) empty() bool {
+	return c.AccessKeyId == "" && c.AccessKeySecret == ""
+}
+
+type aliyunCredentials interface {
+	NewEnvCredentials() credentials
+
+	NewSharedCredentials(filename, profile string) (credentials, error)
+}
+
+type defaultAliyunCredentials struct{}
+
+// NewEnvCredentials reads the credentials from the environment variables used
+// by the Aliyun command line tools
+func (c *defaultAliyunCredentials) NewEnvCredentials() credentials {
+	return credentials{
+		AccessKeyId:     os.Getenv("ALIYUN_ACCESS_KEY_ID"),
+		AccessKeySecret: os.Getenv("ALIYUN_ACCESS_KEY_SECRET"),
+		SecurityToken:   os.Getenv("ALIYUN_SECURITY_TOKEN"),
+	}
+}
+
+// NewSharedCredentials reads the profile from the INI credentials file, which
+// is ~/.aliyun/credentials if filename is empty, e.g.
+//
+//	[default]
+//	access_key_id = ...
+//	access_key_secret = ...
+//	security_token = ...
+func (c *defaultAliyunCredentials) NewSharedCredentials(filename, profile string) (credentials, error) {
+	if filename == "" {
+		filename = defaultCredentialsFile()
+	}
+	if profile == "" {
+		profile = defaultCredentialsProfile
+	}
+
+	file, err := ini.Load(filename)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to load credentials file %s: %v", filename, err)
+	}
+	section, err := file.GetSection(profile)
+	if err != nil {
+		return credentials{}, fmt.Errorf("no profile %s in credentials file %s", profile, filename)
+	}
+
+	creds := credentials{
+		AccessKeyId:     section.Key("access_key_id").String(),
+		AccessKeySecret: section.Key("access_key_secret").String(),
+		SecurityToken:   section.Key("security_token").String(),
+	}
+	if creds.AccessKeyId == "" || creds.AccessKeySecret == "" {
+		return credentials{}, fmt.Errorf("profile %s in credentials file %s requires access_key_id and access_key_secret", profile, filename)
+	}
+	return creds, nil
+}
+
+func defaultCredentialsFile() string {
+	return filepath.Join(mcnutils.GetHomeDir(), ".aliyun", "credentials")
+}
+
+func (d *Driver) credentialsProvider() aliyunCredentials {
+	if d.aliyunCredentials == nil {
+		d.aliyunCredentials = &defaultAliyunCredentials{}
+	}
+	return d.aliyunCredentials
+}
+
+// setCredentialsFromFlags decides where the credentials come from, in the
+// order of the access key options, the profile option, the environment
+// variables and the default profile. Only the access key options are stored
+// with machine, the others are read again whenever the machine is managed.
+func (d *Driver) setCredentialsFromFlags() error {
+	if d.AccessKey != "" || d.SecretKey != "" {
+		if d.CredentialsProfile != "" {
+			return fmt.Errorf("%s | The --aliyunecs-credentials-profile can not be used with --aliyunecs-access-key-id", d.MachineName)
+		}
+		if d.AccessKey == "" {
+			return fmt.Errorf("%s | aliyunecs driver requires the --aliyunecs-access-key-id option", d.MachineName)
+		}
+		if d.SecretKey == "" {
+			return fmt.Errorf("%s | aliyunecs driver requires the --aliyunecs-access-key-secret option", d.MachineName)
+		}
+		return nil
+	}
+
+	if d.SecurityToken != "" {
+		return fmt.Errorf("%s | The --aliyunecs-security-token requires --aliyunecs-access-key-id and --aliyunecs-access-key-secret", d.MachineName)
+	}
+
+	if d.CredentialsProfile == "" {
+		if !d.credentialsProvider().NewEnvCredentials().empty() {
+			_, err := d.credentials()
+			return err
+		}
+		if _, err := d.credentialsProvider().NewSharedCredentials(d.CredentialsFile, defaultCredentialsProfile); err != nil {
+			return fmt.Errorf("%s | %s", d.MachineName, errorMissingCredentials)
+		}
+		d.CredentialsProfile = defaultCredentialsProfile
+	}
+
+	_, err := d.credentials()
+	return err
+}
+
+// credentials returns the credentials to call Aliyun API with
+func (d *Driver) credentials() (credentials, error) {
+	if d.AccessKey != "" || d.SecretKey != "" {
+		// The security token expires soon and is not stored with machine,
+		// it is read from the environment variable of the option again
+		securityToken := d.SecurityToken
+		if securityToken == "" {
+			securityToken = os.Getenv(securityTokenEnvVar)
+		}
+		return credentials{
+			AccessKeyId:     d.AccessKey,
+			AccessKeySecret: d.SecretKey,
+			SecurityToken:   securityToken,
+		}, nil
+	}
+
+	if d.CredentialsProfile != "" {
+		creds, err := d.credentialsProvider().NewSharedCredentials(d.CredentialsFile, d.CredentialsProfile)
+		if err != nil {
+			return creds, fmt.Errorf("%s | Invalid credentials: %v", d.MachineName, err)
+		}
+		return creds, nil
+	}
+
+	creds := d.credentialsProvider().NewEnvCredentials()
+	if creds.AccessKeyId == "" || creds.AccessKeySecret == "" {
+		return creds, fmt.Errorf("%s | %s", d.MachineName, errorMissingCredentials)
+	}
+	return creds, nil
+}
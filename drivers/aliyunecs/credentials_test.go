@@ -0,0 +1,244 @@
+package aliyunecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAliyunCredentials struct {
+	env      credentials
+	profiles map[string]credentials
+}
+
+func (c *fakeAliyunCredentials) NewEnvCredentials() credentials {
+	return c.env
+}
+
+func (c *fakeAliyunCredentials) NewSharedCredentials(filename, profile string) (credentials, error) {
+	creds, ok := c.profiles[profile]
+	if !ok {
+		return credentials{}, fmt.Errorf("no profile %s", profile)
+	}
+	return creds, nil
+}
+
+func getCredentialsTestDriver(t *testing.T, provider aliyunCredentials) *Driver {
+	storePath, err := getTestStorePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDriver(machineTestName, storePath).(*Driver)
+	d.aliyunCredentials = provider
+	return d
+}
+
+func getFlagsWithoutAccessKey() *DriverOptionsMock {
+	flags := getDefaultTestDriverFlags()
+	delete(flags.Data, "aliyunecs-access-key-id")
+	delete(flags.Data, "aliyunecs-access-key-secret")
+	return flags
+}
+
+func TestSetConfigFromFlagsWithProfile(t *testing.T) {
+	defer cleanup()
+	d := getCredentialsTestDriver(t, &fakeAliyunCredentials{
+		profiles: map[string]credentials{
+			"dev": {AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token"},
+		},
+	})
+
+	flags := getFlagsWithoutAccessKey()
+	flags.Data["aliyunecs-credentials-profile"] = "dev"
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.Equal(t, "dev", d.CredentialsProfile)
+
+	creds, err := d.credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", creds.AccessKeySecret)
+	assert.Equal(t, "token", creds.SecurityToken)
+
+	data, err := json.Marshal(d)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.NotContains(t, string(data), "SecurityToken")
+}
+
+func TestSetConfigFromFlagsWithUnknownProfile(t *testing.T) {
+	defer cleanup()
+	d := getCredentialsTestDriver(t, &fakeAliyunCredentials{})
+
+	flags := getFlagsWithoutAccessKey()
+	flags.Data["aliyunecs-credentials-profile"] = "dev"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestSetConfigFromFlagsWithProfileAndAccessKey(t *testing.T) {
+	defer cleanup()
+	d := getCredentialsTestDriver(t, &fakeAliyunCredentials{})
+
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-credentials-profile"] = "dev"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestSetConfigFromFlagsWithEnv(t *testing.T) {
+	defer cleanup()
+	d := getCredentialsTestDriver(t, &fakeAliyunCredentials{
+		env: credentials{AccessKeyId: "id", AccessKeySecret: "secret"},
+		profiles: map[string]credentials{
+			defaultCredentialsProfile: {AccessKeyId: "default-id", AccessKeySecret: "default-secret"},
+		},
+	})
+
+	assert.NoError(t, d.SetConfigFromFlags(getFlagsWithoutAccessKey()))
+	assert.Empty(t, d.AccessKey)
+	assert.Empty(t, d.SecretKey)
+	assert.Empty(t, d.CredentialsProfile)
+
+	creds, err := d.credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "id", creds.AccessKeyId)
+}
+
+func TestSetConfigFromFlagsWithDefaultProfile(t *testing.T) {
+	defer cleanup()
+	d := getCredentialsTestDriver(t, &fakeAliyunCredentials{
+		profiles: map[string]credentials{
+			defaultCredentialsProfile: {AccessKeyId: "default-id", AccessKeySecret: "default-secret"},
+		},
+	})
+
+	assert.NoError(t, d.SetConfigFromFlags(getFlagsWithoutAccessKey()))
+	assert.Equal(t, defaultCredentialsProfile, d.CredentialsProfile)
+	assert.Empty(t, d.SecretKey)
+}
+
+func TestSetConfigFromFlagsWithoutCredentials(t *testing.T) {
+	defer cleanup()
+	d := getCredentialsTestDriver(t, &fakeAliyunCredentials{})
+
+	err := d.SetConfigFromFlags(getFlagsWithoutAccessKey())
+	assert.EqualError(t, err, machineTestName+" | "+errorMissingCredentials)
+}
+
+func TestSetConfigFromFlagsSecurityTokenRequiresAccessKey(t *testing.T) {
+	defer cleanup()
+	d := getCredentialsTestDriver(t, &fakeAliyunCredentials{
+		env: credentials{AccessKeyId: "id", AccessKeySecret: "secret"},
+	})
+
+	flags := getFlagsWithoutAccessKey()
+	flags.Data["aliyunecs-security-token"] = "token"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestSharedCredentialsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aliyunecs-credentials-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "credentials")
+	content := strings.Join([]string{
+		"[default]",
+		"access_key_id = id",
+		"access_key_secret = secret",
+		"",
+		"[sts]",
+		"access_key_id = sts-id",
+		"access_key_secret = sts-secret",
+		"security_token = token",
+		"",
+		"[broken]",
+		"access_key_id = id",
+	}, "\n")
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &defaultAliyunCredentials{}
+
+	creds, err := provider.NewSharedCredentials(filename, "")
+	assert.NoError(t, err)
+	assert.Equal(t, credentials{AccessKeyId: "id", AccessKeySecret: "secret"}, creds)
+
+	creds, err = provider.NewSharedCredentials(filename, "sts")
+	assert.NoError(t, err)
+	assert.Equal(t, credentials{AccessKeyId: "sts-id", AccessKeySecret: "sts-secret", SecurityToken: "token"}, creds)
+
+	_, err = provider.NewSharedCredentials(filename, "broken")
+	assert.Error(t, err)
+
+	_, err = provider.NewSharedCredentials(filename, "missing")
+	assert.Error(t, err)
+
+	_, err = provider.NewSharedCredentials(filepath.Join(dir, "missing"), "")
+	assert.Error(t, err)
+}
+
+// The vendored aliyungo must provide Client.SetSecurityToken of the later
+// upstream revisions, the temporary credentials are rejected without it
+func TestSecurityTokenIsSent(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	securityToken := ""
+	f.handle("DescribeInstanceTypes", func(params url.Values) (int, interface{}) {
+		securityToken = params.Get("SecurityToken")
+		return http.StatusOK, nil
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SecurityToken = "token"
+
+	client, err := d.getClient()
+	assert.NoError(t, err)
+	client.DescribeInstanceTypes()
+	assert.Equal(t, "token", securityToken)
+}
+
+func TestSecurityTokenNotStored(t *testing.T) {
+	d := NewDriver(machineTestName, "").(*Driver)
+	d.AccessKey = "id"
+	d.SecretKey = "secret"
+	d.SecurityToken = "token"
+
+	data, err := json.Marshal(d)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "SecurityToken")
+
+	loaded := NewDriver(machineTestName, "").(*Driver)
+	assert.NoError(t, json.Unmarshal(data, loaded))
+
+	os.Setenv(securityTokenEnvVar, "renewed")
+	defer os.Unsetenv(securityTokenEnvVar)
+	creds, err := loaded.credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "renewed", creds.SecurityToken)
+}
+
+func TestGetClientWithoutCredentials(t *testing.T) {
+	d := NewDriver(machineTestName, "").(*Driver)
+	d.CredentialsProfile = "missing"
+	d.CredentialsFile = filepath.Join(os.TempDir(), "missing-aliyun-credentials")
+
+	_, err := d.getClient()
+	assert.Error(t, err)
+	_, err = d.getSLBClient()
+	assert.Error(t, err)
+	_, err = d.getVPCClient()
+	assert.Error(t, err)
+}
@@ -0,0 +1,233 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/ssh"
+)
+
+const (
+	defaultDataDiskMountPoint = "/var/lib/docker"
+	defaultDataDiskFileSystem = "ext4"
+	maxDataDisks              = 16
+	maxDataDiskSize           = 32768
+)
+
+var mountPointRegexp = regexp.MustCompile(`^/[A-Za-z0-9._/-]+$`)
+
+// DataDisk is the data disk created with instance and mounted on MountPoint
+type DataDisk struct {
+	Size       int
+	Category   ecs.DiskCategory
+	MountPoint string
+	FileSystem string
+	SnapshotId string
+	Device     string
+	DiskId     string
+}
+
+// parseDataDisk parses the value of --aliyunecs-data-disk in the format of
+// size:category:mountpoint[:fs]
+func parseDataDisk(spec string) (DataDisk, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 3 || len(parts) > 4 {
+		return DataDisk{}, fmt.Errorf("the format should be size:category:mountpoint[:fs]")
+	}
+
+	size, err := strconv.Atoi(parts[0])
+	if err != nil || size < 1 || size > maxDataDiskSize {
+		return DataDisk{}, fmt.Errorf("invalid size %q, should be 1 ~ %d", parts[0], maxDataDiskSize)
+	}
+
+	disk := DataDisk{
+		Size:       size,
+		Category:   ecs.DiskCategory(parts[1]),
+		MountPoint: path.Clean(parts[2]),
+		FileSystem: defaultDataDiskFileSystem,
+	}
+
+	switch disk.Category {
+	case "", ecs.DiskCategoryCloud, ecs.DiskCategoryCloudEfficiency, ecs.DiskCategoryCloudSSD, ecs.DiskCategoryEphemeralSSD:
+	default:
+		return DataDisk{}, fmt.Errorf("invalid category %q", parts[1])
+	}
+
+	if !mountPointRegexp.MatchString(disk.MountPoint) {
+		return DataDisk{}, fmt.Errorf("invalid mount point %q", parts[2])
+	}
+
+	if len(parts) == 4 {
+		switch parts[3] {
+		case "ext3", "ext4", "xfs":
+			disk.FileSystem = parts[3]
+		default:
+			return DataDisk{}, fmt.Errorf("invalid file system %q, should be ext3, ext4 or xfs", parts[3])
+		}
+	}
+	return disk, nil
+}
+
+// setDataDisksFromFlags builds the data disks from --aliyunecs-data-disk, and
+// from --aliyunecs-disk-size and --aliyunecs-data-disk-snapshot-id for the
+// disk mounted on /var/lib/docker
+func (d *Driver) setDataDisksFromFlags(specs []string) error {
+	d.DataDisks = nil
+	if d.DiskSize > 0 || d.DataDiskSnapshotId != "" {
+		d.DataDisks = append(d.DataDisks, DataDisk{
+			Size:       d.DiskSize,
+			Category:   d.DiskCategory,
+			MountPoint: defaultDataDiskMountPoint,
+			FileSystem: defaultDataDiskFileSystem,
+			SnapshotId: d.DataDiskSnapshotId,
+		})
+	}
+
+	for _, spec := range specs {
+		disk, err := parseDataDisk(spec)
+		if err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-data-disk %q: %v", d.MachineName, spec, err)
+		}
+		d.DataDisks = append(d.DataDisks, disk)
+	}
+
+	if len(d.DataDisks) > maxDataDisks {
+		return fmt.Errorf("%s | Up to %d data disks are supported", d.MachineName, maxDataDisks)
+	}
+
+	mountPoints := map[string]bool{}
+	for i := range d.DataDisks {
+		disk := &d.DataDisks[i]
+		if mountPoints[disk.MountPoint] {
+			return fmt.Errorf("%s | More than one data disk is mounted on %s", d.MachineName, disk.MountPoint)
+		}
+		mountPoints[disk.MountPoint] = true
+		disk.Device = dataDiskDevice(i)
+	}
+	return nil
+}
+
+// dataDiskDevice returns the device of the ith data disk known by ECS, which
+// is /dev/vd? instead inside the I/O optimized instance
+func dataDiskDevice(i int) string {
+	return "/dev/xvd" + string('b'+rune(i))
+}
+
+func (d *Driver) hasDataDisk() bool {
+	return len(d.DataDisks) > 0 || d.DiskSize > 0 || d.DataDiskSnapshotId != ""
+}
+
+// dataDiskArgs returns the data disks to create instance with
+func (d *Driver) dataDiskArgs() []ecs.DataDiskType {
+	args := []ecs.DataDiskType{}
+	for i, disk := range d.DataDisks {
+		args = append(args, ecs.DataDiskType{
+			DiskName:           fmt.Sprintf("%s_data%d", d.MachineName, i),
+			Description:        "Data volume for " + disk.MountPoint,
+			Size:               disk.Size,
+			Category:           disk.Category,
+			SnapshotId:         disk.SnapshotId,
+			Device:             disk.Device,
+			DeleteWithInstance: true,
+		})
+	}
+	if len(args) == 1 && d.DataDisks[0].MountPoint == defaultDataDiskMountPoint {
+		// Keep the name of the only data disk for Docker as it was
+		args[0].DiskName = d.MachineName + "_data"
+		args[0].Description = "Data volume for Docker"
+	}
+	return args
+}
+
+// describeDataDisks returns the data disks attached to instance
+func (d *Driver) describeDataDisks() ([]ecs.DiskItemType, error) {
+	args := ecs.DescribeDisksArgs{
+		RegionId:   d.Region,
+		InstanceId: d.InstanceId,
+		DiskType:   ecs.DiskTypeAllData,
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	disks, _, err := client.DescribeDisks(&args)
+	return disks, err
+}
+
+// recordDataDisks records the id of data disks by device, the disk mounted on
+// /var/lib/docker is the one to take snapshot of
+func (d *Driver) recordDataDisks() error {
+	disks, err := d.describeDataDisks()
+	if err != nil {
+		return err
+	}
+	for i := range d.DataDisks {
+		for _, disk := range disks {
+			if disk.Device == d.DataDisks[i].Device {
+				d.DataDisks[i].DiskId = disk.DiskId
+			}
+		}
+	}
+
+	d.DataDiskId = ""
+	for _, disk := range d.DataDisks {
+		if disk.MountPoint == defaultDataDiskMountPoint {
+			d.DataDiskId = disk.DiskId
+		}
+	}
+	if d.DataDiskId == "" && len(d.DataDisks) > 0 {
+		d.DataDiskId = d.DataDisks[0].DiskId
+	}
+	return nil
+}
+
+// keepDataDisks stops the data disks from being released with instance
+func (d *Driver) keepDataDisks() {
+	disks, err := d.describeDataDisks()
+	if err != nil {
+		log.Errorf("%s | Failed to describe data disks of instance %s: %v", d.MachineName, d.InstanceId, err)
+		return
+	}
+	deleteWithInstance := false
+	for _, disk := range disks {
+		args := ecs.ModifyDiskAttributeArgs{
+			DiskId:             disk.DiskId,
+			DeleteWithInstance: &deleteWithInstance,
+		}
+		if err := d.modifyDiskAttribute(&args); err != nil {
+			log.Errorf("%s | Failed to keep data disk %s: %v", d.MachineName, disk.DiskId, err)
+			continue
+		}
+		log.Infof("%s | Data disk %s is kept, please release it in console when it is no longer used", d.MachineName, disk.DiskId)
+	}
+}
+
+// fdiskScript returns the script to format and mount the data disks, which
+// fails if any disk is not mounted
+func (d *Driver) fdiskScript() string {
+	script := autoFdiskScript + "FAILED=0\n"
+	for i, disk := range d.DataDisks {
+		script += fmt.Sprintf("mount_disk %c %s %s || FAILED=1\n", 'b'+rune(i), disk.MountPoint, disk.FileSystem)
+	}
+	return script + "df -h\nexit $FAILED\n"
+}
+
+// Mount the addtional disk
+func (d *Driver) autoFdisk(sshClient ssh.Client) error {
+	script := fmt.Sprintf("cat > ~/machine_autofdisk.sh <<'MACHINE_EOF'\n%s\nMACHINE_EOF\n", d.fdiskScript())
+	output, err := sshClient.Output(script)
+	if err != nil {
+		return fmt.Errorf("failed to upload script: %v: %s", err, output)
+	}
+	output, err = sshClient.Output("bash ~/machine_autofdisk.sh")
+	log.Debugf("%s | Auto Fdisk command err, output: %v: %s", d.MachineName, err, output)
+	if err != nil {
+		return fmt.Errorf("failed to mount data disks: %v: %s", err, output)
+	}
+	return nil
+}
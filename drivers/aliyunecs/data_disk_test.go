@@ -0,0 +1,146 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDataDisk(t *testing.T) {
+	var tests = []struct {
+		spec     string
+		expected DataDisk
+		valid    bool
+	}{
+		{"100:cloud_ssd:/data", DataDisk{Size: 100, Category: ecs.DiskCategoryCloudSSD, MountPoint: "/data", FileSystem: "ext4"}, true},
+		{"40::/var/lib/docker/:xfs", DataDisk{Size: 40, MountPoint: "/var/lib/docker", FileSystem: "xfs"}, true},
+		{"20:cloud_efficiency:/mnt/logs:ext3", DataDisk{Size: 20, Category: ecs.DiskCategoryCloudEfficiency, MountPoint: "/mnt/logs", FileSystem: "ext3"}, true},
+		{"100:cloud_ssd", DataDisk{}, false},
+		{"100:cloud_ssd:/data:ext4:extra", DataDisk{}, false},
+		{"big:cloud_ssd:/data", DataDisk{}, false},
+		{"0:cloud_ssd:/data", DataDisk{}, false},
+		{"100:floppy:/data", DataDisk{}, false},
+		{"100:cloud_ssd:data", DataDisk{}, false},
+		{"100:cloud_ssd:/", DataDisk{}, false},
+		{"100:cloud_ssd:/my data", DataDisk{}, false},
+		{"100:cloud_ssd:/data:ntfs", DataDisk{}, false},
+	}
+
+	for _, test := range tests {
+		disk, err := parseDataDisk(test.spec)
+		if !test.valid {
+			assert.Error(t, err, test.spec)
+			continue
+		}
+		assert.NoError(t, err, test.spec)
+		assert.Equal(t, test.expected, disk, test.spec)
+	}
+}
+
+func TestSetDataDisksFromFlags(t *testing.T) {
+	d := &Driver{
+		BaseDriver:         &drivers.BaseDriver{MachineName: "test"},
+		DiskSize:           40,
+		DiskCategory:       ecs.DiskCategoryCloudSSD,
+		DataDiskSnapshotId: "s-cache",
+	}
+
+	err := d.setDataDisksFromFlags([]string{"100:cloud_efficiency:/data", "20::/logs:xfs"})
+	assert.NoError(t, err)
+	assert.Equal(t, []DataDisk{
+		{Size: 40, Category: ecs.DiskCategoryCloudSSD, MountPoint: "/var/lib/docker", FileSystem: "ext4", SnapshotId: "s-cache", Device: "/dev/xvdb"},
+		{Size: 100, Category: ecs.DiskCategoryCloudEfficiency, MountPoint: "/data", FileSystem: "ext4", Device: "/dev/xvdc"},
+		{Size: 20, MountPoint: "/logs", FileSystem: "xfs", Device: "/dev/xvdd"},
+	}, d.DataDisks)
+
+	args := d.dataDiskArgs()
+	assert.Len(t, args, 3)
+	assert.Equal(t, "s-cache", args[0].SnapshotId)
+	assert.Equal(t, "/dev/xvdc", args[1].Device)
+	assert.True(t, args[2].DeleteWithInstance)
+
+	err = d.setDataDisksFromFlags([]string{"100:cloud_efficiency:/var/lib/docker"})
+	assert.Error(t, err)
+
+	d.DiskSize = 0
+	d.DataDiskSnapshotId = ""
+	specs := []string{}
+	for i := 0; i <= maxDataDisks; i++ {
+		specs = append(specs, "20::/data"+string('a'+rune(i)))
+	}
+	assert.Error(t, d.setDataDisksFromFlags(specs))
+	assert.NoError(t, d.setDataDisksFromFlags(specs[:maxDataDisks]))
+	assert.Equal(t, "/dev/xvdq", d.DataDisks[maxDataDisks-1].Device)
+}
+
+func TestFdiskScript(t *testing.T) {
+	d := &Driver{
+		BaseDriver: &drivers.BaseDriver{MachineName: "test"},
+		DiskSize:   40,
+	}
+	assert.NoError(t, d.setDataDisksFromFlags([]string{"100:cloud_ssd:/data:xfs"}))
+
+	script := d.fdiskScript()
+	assert.True(t, strings.HasPrefix(script, autoFdiskScript))
+	assert.Contains(t, script, "mount_disk b /var/lib/docker ext4 || FAILED=1\n")
+	assert.Contains(t, script, "mount_disk c /data xfs || FAILED=1\n")
+	assert.True(t, strings.HasSuffix(script, "exit $FAILED\n"))
+}
+
+func TestRecordDataDisks(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	disks := ecs.DescribeDisksResponse{}
+	disks.Disks.Disk = []ecs.DiskItemType{
+		{DiskId: "d-data", Device: "/dev/xvdb", Type: ecs.DiskTypeAllData},
+		{DiskId: "d-docker", Device: "/dev/xvdc", Type: ecs.DiskTypeAllData},
+	}
+	f.respond("DescribeDisks", disks)
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+	assert.NoError(t, d.setDataDisksFromFlags([]string{"100::/data", "40::/var/lib/docker"}))
+
+	assert.NoError(t, d.recordDataDisks())
+	assert.Equal(t, "d-data", d.DataDisks[0].DiskId)
+	assert.Equal(t, "d-docker", d.DataDisks[1].DiskId)
+	assert.Equal(t, "d-docker", d.DataDiskId)
+}
+
+func TestRemoveKeepsDataDisks(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.respond("DescribeInstanceAttribute", ecs.InstanceAttributesType{InstanceId: "i-test", Status: ecs.Stopped})
+
+	disks := ecs.DescribeDisksResponse{}
+	disks.Disks.Disk = []ecs.DiskItemType{{DiskId: "d-data", Device: "/dev/xvdb", Type: ecs.DiskTypeAllData}}
+	f.respond("DescribeDisks", disks)
+
+	kept := []string{}
+	f.handle("ModifyDiskAttribute", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "false", params.Get("DeleteWithInstance"))
+		kept = append(kept, params.Get("DiskId"))
+		return http.StatusOK, struct{}{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+	d.KeepDataDisks = true
+
+	assert.NoError(t, d.Remove())
+	assert.Equal(t, []string{"d-data"}, kept)
+	assert.True(t, f.hasCalled("DeleteInstance"))
+}
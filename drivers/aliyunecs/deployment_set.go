@@ -0,0 +1,132 @@
+package aliyunecs
+
+import (
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// The instances in a deployment set of this strategy are spread across
+// physical hosts
+const deploymentSetStrategy = "Availability"
+
+// swarmDeploymentSetName returns the name of the deployment set shared by the
+// machines of a swarm, which is keyed by the discovery token
+func swarmDeploymentSetName(discovery string) string {
+	sum := sha1.Sum([]byte(discovery))
+	return fmt.Sprintf("docker-machine-swarm-%x", sum[:6])
+}
+
+func (d *Driver) getDeploymentSet(deploymentSetId string) (*deploymentSet, error) {
+	sets, err := d.describeDeploymentSets(&describeDeploymentSetsArgs{
+		RegionId:         d.Region,
+		DeploymentSetIds: []string{deploymentSetId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("deployment set %s not found in region %s", deploymentSetId, d.Region)
+	}
+	return &sets[0], nil
+}
+
+// findDeploymentSet returns the deployment set with the name, nil if not found
+func (d *Driver) findDeploymentSet(name string) (*deploymentSet, error) {
+	sets, err := d.describeDeploymentSets(&describeDeploymentSetsArgs{
+		RegionId:          d.Region,
+		DeploymentSetName: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range sets {
+		if sets[i].DeploymentSetName == name {
+			return &sets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// checkDeploymentSet checks the deployment set of --aliyunecs-deployment-set-id,
+// or looks up the one of swarm created by the other machines
+func (d *Driver) checkDeploymentSet() error {
+	if d.SwarmDeploymentSet {
+		name := swarmDeploymentSetName(d.SwarmDiscovery)
+		set, err := d.findDeploymentSet(name)
+		if err != nil {
+			return fmt.Errorf("%s | Failed to describe deployment set %s: %v", d.MachineName, name, err)
+		}
+		d.DeploymentSetId = ""
+		if set != nil {
+			d.DeploymentSetId = set.DeploymentSetId
+		}
+		return nil
+	}
+
+	if d.DeploymentSetId == "" {
+		return nil
+	}
+	if _, err := d.getDeploymentSet(d.DeploymentSetId); err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-deployment-set-id: %v", d.MachineName, err)
+	}
+	return nil
+}
+
+// configureDeploymentSet creates the deployment set of swarm unless it is
+// created by the other machines already
+func (d *Driver) configureDeploymentSet() error {
+	if !d.SwarmDeploymentSet || d.DeploymentSetId != "" {
+		return nil
+	}
+
+	name := swarmDeploymentSetName(d.SwarmDiscovery)
+	log.Infof("%s | Creating deployment set %s for swarm ...", d.MachineName, name)
+	deploymentSetId, err := d.createDeploymentSet(&createDeploymentSetArgs{
+		RegionId:          d.Region,
+		DeploymentSetName: name,
+		Description:       "Deployment set of swarm created by Docker Machine",
+		Strategy:          deploymentSetStrategy,
+		// The same token keeps the machines created in parallel from
+		// creating more than one deployment set
+		ClientToken: name,
+	})
+	if err != nil {
+		return fmt.Errorf("%s | Failed to create deployment set %s: %v", d.MachineName, name, err)
+	}
+	d.DeploymentSetId = deploymentSetId
+	d.rollback.add("deployment set "+deploymentSetId, func() error {
+		return d.removeUnusedDeploymentSet(deploymentSetId)
+	})
+	return nil
+}
+
+// removeUnusedDeploymentSet deletes the deployment set without instances
+func (d *Driver) removeUnusedDeploymentSet(deploymentSetId string) error {
+	set, err := d.getDeploymentSet(deploymentSetId)
+	if err != nil {
+		return err
+	}
+	if set.InstanceAmount > 0 {
+		log.Infof("%s | Deployment set %s is still used by %d instance(s)", d.MachineName, deploymentSetId, set.InstanceAmount)
+		return nil
+	}
+
+	log.Infof("%s | Deleting deployment set %s ...", d.MachineName, deploymentSetId)
+	return retry(func() error { return d.deleteDeploymentSet(deploymentSetId) })
+}
+
+// cleanupDeploymentSet deletes the deployment set of swarm after the last
+// machine is removed
+func (d *Driver) cleanupDeploymentSet(instanceId string) error {
+	if !d.SwarmDeploymentSet || d.DeploymentSetId == "" {
+		return nil
+	}
+
+	if err := d.waitForInstanceDeleted(instanceId); err != nil {
+		return err
+	}
+
+	return d.removeUnusedDeploymentSet(d.DeploymentSetId)
+}
@@ -0,0 +1,143 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfigFromFlagsDeploymentSet(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-deployment-set-id"] = "ds-test"
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.Equal(t, "ds-test", d.DeploymentSetId)
+
+	flags.Data["aliyunecs-swarm-deployment-set"] = true
+	assert.Error(t, d.SetConfigFromFlags(flags))
+
+	flags.Data["aliyunecs-deployment-set-id"] = ""
+	assert.Error(t, d.SetConfigFromFlags(flags))
+
+	flags.Data["swarm-discovery"] = "token://abc"
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.True(t, d.SwarmDeploymentSet)
+}
+
+func TestSwarmDeploymentSetName(t *testing.T) {
+	name := swarmDeploymentSetName("token://abc")
+	assert.Equal(t, name, swarmDeploymentSetName("token://abc"))
+	assert.NotEqual(t, name, swarmDeploymentSetName("token://def"))
+	assert.Len(t, name, len("docker-machine-swarm-")+12)
+}
+
+func fakeDeploymentSets(f *fakeECS, sets ...deploymentSet) {
+	f.handle("DescribeDeploymentSets", func(params url.Values) (int, interface{}) {
+		response := describeDeploymentSetsResponse{}
+		for _, set := range sets {
+			if params.Get("DeploymentSetIds") == `["`+set.DeploymentSetId+`"]` || params.Get("DeploymentSetName") == set.DeploymentSetName {
+				response.DeploymentSets.DeploymentSet = append(response.DeploymentSets.DeploymentSet, set)
+			}
+		}
+		return http.StatusOK, response
+	})
+}
+
+func TestCheckDeploymentSet(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeDeploymentSets(f, deploymentSet{DeploymentSetId: "ds-test", DeploymentSetName: "test"})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+
+	d.DeploymentSetId = "ds-test"
+	assert.NoError(t, d.checkDeploymentSet())
+
+	d.DeploymentSetId = "ds-missing"
+	assert.Error(t, d.checkDeploymentSet())
+}
+
+func TestConfigureSwarmDeploymentSet(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeDeploymentSets(f)
+
+	name := swarmDeploymentSetName("token://abc")
+	f.handle("CreateDeploymentSet", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, name, params.Get("DeploymentSetName"))
+		assert.Equal(t, name, params.Get("ClientToken"))
+		assert.Equal(t, deploymentSetStrategy, params.Get("Strategy"))
+		return http.StatusOK, createDeploymentSetResponse{DeploymentSetId: "ds-new"}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SwarmDeploymentSet = true
+	d.SwarmDiscovery = "token://abc"
+
+	assert.NoError(t, d.checkDeploymentSet())
+	assert.Empty(t, d.DeploymentSetId)
+	assert.NoError(t, d.configureDeploymentSet())
+	assert.Equal(t, "ds-new", d.DeploymentSetId)
+}
+
+func TestJoinSwarmDeploymentSet(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeDeploymentSets(f, deploymentSet{DeploymentSetId: "ds-swarm", DeploymentSetName: swarmDeploymentSetName("token://abc")})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SwarmDeploymentSet = true
+	d.SwarmDiscovery = "token://abc"
+
+	assert.NoError(t, d.checkDeploymentSet())
+	assert.Equal(t, "ds-swarm", d.DeploymentSetId)
+	assert.NoError(t, d.configureDeploymentSet())
+	assert.False(t, f.hasCalled("CreateDeploymentSet"))
+}
+
+func TestCleanupDeploymentSet(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeDeploymentSets(f, deploymentSet{DeploymentSetId: "ds-swarm", InstanceAmount: 1})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SwarmDeploymentSet = true
+	d.DeploymentSetId = "ds-swarm"
+
+	assert.NoError(t, d.cleanupDeploymentSet("i-test"))
+	assert.False(t, f.hasCalled("DeleteDeploymentSet"))
+
+	fakeDeploymentSets(f, deploymentSet{DeploymentSetId: "ds-swarm"})
+	assert.NoError(t, d.cleanupDeploymentSet("i-test"))
+	assert.True(t, f.hasCalled("DeleteDeploymentSet"))
+}
+
+func TestCreateRollbackDeploymentSet(t *testing.T) {
+	f := newFakeCreateECS()
+	defer f.Close()
+	fakeDeploymentSets(f, deploymentSet{DeploymentSetId: "ds-new"})
+	f.respond("CreateDeploymentSet", createDeploymentSetResponse{DeploymentSetId: "ds-new"})
+	f.handle("CreateInstance", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "ds-new", params.Get("DeploymentSetId"))
+		return http.StatusBadRequest, common.ErrorResponse{Code: "InvalidParameter"}
+	})
+
+	d := getFakeCreateDriver(t, f)
+	defer os.RemoveAll(d.StorePath)
+	d.CreateVPC = true
+	d.SwarmDeploymentSet = true
+	d.SwarmDiscovery = "token://abc"
+
+	assert.Error(t, d.Create())
+	assert.True(t, f.hasCalled("CreateInstance"))
+	assert.True(t, f.hasCalled("DeleteDeploymentSet"))
+}
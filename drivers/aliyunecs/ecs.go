@@ -3,6 +3,7 @@ package aliyunecs
 import (
 	"crypto/md5"
 	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	mrand "math/rand"
 
@@ -11,10 +12,7 @@ import (
 	"github.com/denverdino/aliyungo/slb"
 
 	"io"
-	"io/ioutil"
 	"net"
-	"net/url"
-	"strconv"
 	"strings"
 	"time"
 
@@ -32,7 +30,7 @@ const (
 	defaultRegion            = "cn-hangzhou"
 	defaultInstanceType      = "ecs.t1.small"
 	defaultRootSize          = 20
-	internetChargeType       = "PayByTraffic"
+	maxInternetBandwidth     = 100
 	ipRange                  = "0.0.0.0/0"
 	machineSecurityGroupName = "docker-machine"
 	vpcCidrBlock             = "10.0.0.0/8"
@@ -52,35 +50,87 @@ type Driver struct {
 	Id                      string
 	AccessKey               string
 	SecretKey               string
+	SecurityToken           string `json:"-"`
+	CredentialsProfile      string
+	CredentialsFile         string
 	Region                  common.Region
 	ImageID                 string
+	ImageEngineInstalled    bool
+	ImageName               string
+	ImageOwner              string
 	SSHPassword             string
+	SSHBastion              string
+	SSHPrivateKeyPath       string
+	EipId                   string
+	EipAddress              string
+	ExistingEip             bool
+	KeyPairName             string
+	ExistingKeyPair         bool
 	PublicKey               []byte
 	InstanceId              string
+	AdoptedInstance         bool
+	ImportTags              map[string]string
+	DeleteAdoptedInstance   bool
 	InstanceType            string
+	InstanceTypeAuto        bool
+	MinCPU                  int
+	MinMemory               int
 	PrivateIPAddress        string
 	SecurityGroupId         string
 	SecurityGroupName       string
+	ExistingSecurityGroup   bool
+	MachineSecurityGroup    bool
+	OpenPorts               []string
+	SourceCidr              string
 	ReservationId           string
 	VpcId                   string
 	VSwitchId               string
+	VpcOwned                bool
+	VSwitchOwned            bool
+	CreateVPC               bool
 	Zone                    string
 	PrivateIPOnly           bool
 	InternetMaxBandwidthOut int
+	InternetChargeType      common.InternetChargeType
+	InstanceChargeType      InstanceChargeType
+	Period                  int
+	AutoRenew               bool
 	RouteCIDR               string
+	RouteCIDRPool           string
+	RouteCIDRSize           int
 	SLBID                   string
 	SLBIPAddress            string
+	SLBAttachments          []SLBAttachment
+	SLBListeners            []SLBListener
+	SLBAPIEndpoint          string
 	Tags                    map[string]string
 	DiskSize                int
+	DataDiskId              string
+	DataDiskSnapshotId      string
+	DataDisks               []DataDisk
+	KeepDataDisks           bool
 	UpgradeKernel           bool
+	PrepareSteps            []string
 	DiskCategory            ecs.DiskCategory
 	Description             string
+	UserData                string
 	IoOptimized             bool
 	APIEndpoint             string
 	SystemDiskCategory      ecs.DiskCategory
-
-	client    *ecs.Client
-	slbClient *slb.Client
+	KeepOnFailure           bool
+	SpotStrategy            SpotStrategy
+	SpotPriceLimit          float64
+	RamRoleName             string
+	DeploymentSetId         string
+	SwarmDeploymentSet      bool
+
+	client            *ecs.Client
+	slbClient         *slb.Client
+	vpcClient         *common.Client
+	ramClient         *common.Client
+	rollback          *rollback
+	prereqsChecked    bool
+	aliyunCredentials aliyunCredentials
 }
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
@@ -97,11 +147,56 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  "",
 			EnvVar: "ECS_ACCESS_KEY_SECRET",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-security-token",
+			Usage:  "STS security token of temporary access key",
+			Value:  "",
+			EnvVar: securityTokenEnvVar,
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-credentials-profile",
+			Usage:  "Profile in the credentials file to read access key from",
+			Value:  "",
+			EnvVar: "ECS_CREDENTIALS_PROFILE",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-credentials-file",
+			Usage:  "Credentials file, default is ~/.aliyun/credentials",
+			Value:  "",
+			EnvVar: "ECS_CREDENTIALS_FILE",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-image-id",
 			Usage:  "ECS machine image",
 			EnvVar: "ECS_IMAGE_ID",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-image-name",
+			Usage:  "Glob pattern or /regular expression/ of image name, the newest matched image is used",
+			EnvVar: "ECS_IMAGE_NAME",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-image-owner",
+			Usage:  "Owner of image to match: system, self, others or marketplace",
+			Value:  string(ecs.ImageOwnerSystem),
+			EnvVar: "ECS_IMAGE_OWNER",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-instance-id",
+			Usage:  "Existing ECS instance to adopt instead of creating a new one",
+			EnvVar: "ECS_INSTANCE_ID",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "aliyunecs-import-tag",
+			Usage:  "Tag in the form key=value to find the existing ECS instance to adopt",
+			Value:  []string{},
+			EnvVar: "ECS_IMPORT_TAGS",
+		},
+		mcnflag.BoolFlag{
+			Name:   "aliyunecs-delete-adopted-instance",
+			Usage:  "Delete the adopted instance on removal instead of detaching it",
+			EnvVar: "ECS_DELETE_ADOPTED_INSTANCE",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-region",
 			Usage:  "ECS region, default cn-hangzhou",
@@ -120,6 +215,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  "",
 			EnvVar: "ECS_VSWITCH_ID",
 		},
+		mcnflag.BoolFlag{
+			Name:   "aliyunecs-create-vpc",
+			Usage:  "Create the VPC and VSwitch for instance automatically",
+			EnvVar: "ECS_CREATE_VPC",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-zone",
 			Usage:  "ECS zone for instance",
@@ -132,12 +232,46 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  "docker-machine",
 			EnvVar: "ECS_SECURITY_GROUP",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-security-group-id",
+			Usage:  "Existing ECS security group id to use instead of looking up by name",
+			EnvVar: "ECS_SECURITY_GROUP_ID",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "aliyunecs-open-port",
+			Usage:  "Make the specified port number (port[-port][/tcp|udp]) accessible from the source CIDR",
+			Value:  []string{},
+			EnvVar: "ECS_OPEN_PORTS",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-source-cidr",
+			Usage:  "Source CIDR allowed to access the instance",
+			Value:  ipRange,
+			EnvVar: "ECS_SOURCE_CIDR",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-instance-type",
 			Usage:  "ECS instance type",
 			Value:  defaultInstanceType,
 			EnvVar: "ECS_INSTANCE_TYPE",
 		},
+		mcnflag.BoolFlag{
+			Name:   "aliyunecs-instance-type-auto",
+			Usage:  "Select the cheapest available instance type with the minimum CPU and memory",
+			EnvVar: "ECS_INSTANCE_TYPE_AUTO",
+		},
+		mcnflag.IntFlag{
+			Name:   "aliyunecs-min-cpu",
+			Usage:  "Minimum number of CPU cores for --aliyunecs-instance-type-auto",
+			Value:  1,
+			EnvVar: "ECS_MIN_CPU",
+		},
+		mcnflag.IntFlag{
+			Name:   "aliyunecs-min-memory",
+			Usage:  "Minimum memory size (in GB) for --aliyunecs-instance-type-auto",
+			Value:  1,
+			EnvVar: "ECS_MIN_MEMORY",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-private-ip",
 			Usage:  "ECS VPC instance private IP",
@@ -150,32 +284,114 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  "",
 			EnvVar: "ECS_DESCRIPTION",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-userdata",
+			Usage:  "User data (path to file or inline content) to initialize instance with cloud-init",
+			EnvVar: "ECS_USERDATA",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-ssh-password",
 			Usage:  "set the password of the ssh user",
 			EnvVar: "ECS_SSH_PASSWORD",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-keypair-name",
+			Usage:  "Name of an existing key pair to launch instance with instead of importing the SSH key of machine",
+			Value:  "",
+			EnvVar: "ECS_KEYPAIR_NAME",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-ssh-keypath",
+			Usage:  "Path of the private key of --aliyunecs-keypair-name",
+			Value:  "",
+			EnvVar: "ECS_SSH_KEYPATH",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-ssh-bastion",
+			Usage:  "SSH bastion in the form user@host[:port] to reach the instance through",
+			Value:  "",
+			EnvVar: "ECS_SSH_BASTION",
+		},
 		mcnflag.BoolFlag{
 			Name:   "aliyunecs-private-address-only",
 			EnvVar: "ECS_PRIVATE_ADDR_ONLY",
 			Usage:  "Only use a private IP address",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-eip-id",
+			Usage:  "Allocation ID of an existing EIP to associate with the instance in VPC",
+			Value:  "",
+			EnvVar: "ECS_EIP_ID",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-eip-address",
+			Usage:  "IP address of an existing EIP to associate with the instance in VPC",
+			Value:  "",
+			EnvVar: "ECS_EIP_ADDRESS",
+		},
 		mcnflag.IntFlag{
 			Name:   "aliyunecs-internet-max-bandwidth",
 			Usage:  "Maxium bandwidth for Internet access (in Mbps), default 1",
 			Value:  1,
 			EnvVar: "ECS_INTERNET_MAX_BANDWIDTH",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-internet-charge-type",
+			Usage:  "Internet charge type: PayByTraffic or PayByBandwidth",
+			Value:  string(common.PayByTraffic),
+			EnvVar: "ECS_INTERNET_CHARGE_TYPE",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-instance-charge-type",
+			Usage:  "Instance charge type: PostPaid or PrePaid",
+			Value:  string(PostPaid),
+			EnvVar: "ECS_INSTANCE_CHARGE_TYPE",
+		},
+		mcnflag.IntFlag{
+			Name:   "aliyunecs-period",
+			Usage:  "Subscription period of PrePaid instance in months",
+			Value:  0,
+			EnvVar: "ECS_PERIOD",
+		},
+		mcnflag.BoolFlag{
+			Name:   "aliyunecs-auto-renew",
+			Usage:  "Renew the PrePaid instance automatically on expiration",
+			EnvVar: "ECS_AUTO_RENEW",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-route-cidr",
 			Usage:  "Docker bridge CIDR for route entry in VPC",
 			EnvVar: "ECS_ROUTE_CIDR",
 		},
 		mcnflag.StringFlag{
+			Name:   "aliyunecs-route-cidr-pool",
+			Usage:  "Pool to allocate the Docker bridge CIDR for route entry in VPC from",
+			EnvVar: "ECS_ROUTE_CIDR_POOL",
+		},
+		mcnflag.IntFlag{
+			Name:   "aliyunecs-route-cidr-size",
+			Usage:  "Prefix length of the Docker bridge CIDR allocated from --aliyunecs-route-cidr-pool",
+			Value:  defaultRouteCIDRSize,
+			EnvVar: "ECS_ROUTE_CIDR_SIZE",
+		},
+		mcnflag.StringSliceFlag{
 			Name:   "aliyunecs-slb-id",
-			Usage:  "SLB id for instance association",
+			Usage:  "SLB id[:weight] for instance association",
+			Value:  []string{},
 			EnvVar: "ECS_SLB_ID",
 		},
+		mcnflag.StringSliceFlag{
+			Name:   "aliyunecs-slb-listener",
+			Usage:  "TCP listener port[:backend-port] to create on the SLBs",
+			Value:  []string{},
+			EnvVar: "ECS_SLB_LISTENERS",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-slb-api-endpoint",
+			Usage:  "Custom SLB API endpoint, default to the custom API endpoint",
+			Value:  "",
+			EnvVar: "ECS_SLB_API_ENDPOINT",
+		},
 		mcnflag.StringSliceFlag{
 			Name:   "aliyunecs-tag",
 			Usage:  "Tags for instance",
@@ -188,6 +404,22 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  0,
 			EnvVar: "ECS_DISK_SIZE",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-data-disk-snapshot-id",
+			Usage:  "Snapshot id to create the data disk for instance from",
+			EnvVar: "ECS_DATA_DISK_SNAPSHOT_ID",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "aliyunecs-data-disk",
+			Usage:  "Data disk to create in the format of size:category:mountpoint[:fs]",
+			Value:  []string{},
+			EnvVar: "ECS_DATA_DISKS",
+		},
+		mcnflag.BoolFlag{
+			Name:   "aliyunecs-keep-data-disk",
+			Usage:  "Keep the data disks when the machine is removed",
+			EnvVar: "ECS_KEEP_DATA_DISKS",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-system-disk-category",
 			Usage:  "System disk category for instance",
@@ -200,15 +432,55 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		},
 		mcnflag.BoolFlag{
 			Name:   "aliyunecs-upgrade-kernel",
-			Usage:  "Upgrade kernel for instance (Ubuntu 14.04 only)",
+			Usage:  "Upgrade kernel for instance, the same as adding kernel to --aliyunecs-prepare",
 			EnvVar: "ECS_UPGRADE_KERNEL",
 		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-prepare",
+			Usage:  "Comma separated steps to prepare the host: fdisk, routes, kernel or none",
+			Value:  strings.Join(defaultPrepareSteps, ","),
+			EnvVar: "ECS_PREPARE",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-io-optimized",
 			Usage:  "I/O optimized instance",
 			Value:  "",
 			EnvVar: "ECS_IO_OPTIMIZED",
 		},
+		mcnflag.BoolFlag{
+			Name:   "aliyunecs-keep-on-failure",
+			Usage:  "Keep the allocated resources for debugging if the creation fails",
+			EnvVar: "ECS_KEEP_ON_FAILURE",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-spot-strategy",
+			Usage:  "Spot strategy of instance: NoSpot, SpotWithPriceLimit or SpotAsPriceGo",
+			Value:  "",
+			EnvVar: "ECS_SPOT_STRATEGY",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-spot-price-limit",
+			Usage:  "Maximum hourly price of spot instance",
+			Value:  "",
+			EnvVar: "ECS_SPOT_PRICE_LIMIT",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-ram-role-name",
+			Usage:  "RAM role to attach to the instance",
+			Value:  "",
+			EnvVar: "ECS_RAM_ROLE_NAME",
+		},
+		mcnflag.StringFlag{
+			Name:   "aliyunecs-deployment-set-id",
+			Usage:  "Deployment set to spread the instance across physical hosts",
+			Value:  "",
+			EnvVar: "ECS_DEPLOYMENT_SET_ID",
+		},
+		mcnflag.BoolFlag{
+			Name:   "aliyunecs-swarm-deployment-set",
+			Usage:  "Create or join the deployment set shared by the machines with the same --swarm-discovery",
+			EnvVar: "ECS_SWARM_DEPLOYMENT_SET",
+		},
 		mcnflag.StringFlag{
 			Name:   "aliyunecs-api-endpoint",
 			Usage:  "Custom API endpoint",
@@ -229,43 +501,6 @@ func NewDriver(hostName, storePath string) drivers.Driver {
 		}}
 }
 
-func (d *Driver) GetImageID(image string) string {
-
-	if len(image) != 0 {
-		return image
-	}
-	args := ecs.DescribeImagesArgs{
-		RegionId:        d.Region,
-		ImageOwnerAlias: ecs.ImageOwnerSystem,
-	}
-
-	// Scan registed images with prefix of ubuntu1404_64_20G_
-	for {
-		images, pagination, err := d.getClient().DescribeImages(&args)
-		if err != nil {
-			log.Errorf("%s | Failed to describe images: %v", d.MachineName, err)
-			break
-		} else {
-			for _, image := range images {
-				if strings.HasPrefix(image.ImageId, defaultUbuntuImagePrefix) {
-					return image.ImageId
-				}
-			}
-			nextPage := pagination.NextPage()
-			if nextPage == nil {
-				break
-			}
-			args.Pagination = *nextPage
-		}
-	}
-
-	//Default use the config Ubuntu 14.04 64bits image
-
-	image = defaultUbuntuImageID
-
-	return image
-}
-
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.APIEndpoint = flags.String("aliyunecs-api-endpoint")
 	var region common.Region
@@ -282,52 +517,150 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	}
 	d.AccessKey = flags.String("aliyunecs-access-key-id")
 	d.SecretKey = flags.String("aliyunecs-access-key-secret")
+	d.SecurityToken = flags.String("aliyunecs-security-token")
+	d.CredentialsProfile = flags.String("aliyunecs-credentials-profile")
+	d.CredentialsFile = flags.String("aliyunecs-credentials-file")
 	d.Region = region
 	d.ImageID = flags.String("aliyunecs-image-id")
+	d.ImageName = flags.String("aliyunecs-image-name")
+	d.ImageOwner = flags.String("aliyunecs-image-owner")
+	d.InstanceId = flags.String("aliyunecs-instance-id")
+	importTags, err := parseTags(flags.StringSlice("aliyunecs-import-tag"))
+	if err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-import-tag: %v", d.MachineName, err)
+	}
+	if len(importTags) > 0 {
+		if d.InstanceId != "" {
+			return fmt.Errorf("%s | The --aliyunecs-import-tag can not be used with --aliyunecs-instance-id", d.MachineName)
+		}
+		d.ImportTags = importTags
+	}
+	d.AdoptedInstance = d.InstanceId != "" || len(d.ImportTags) > 0
+	d.DeleteAdoptedInstance = flags.Bool("aliyunecs-delete-adopted-instance")
 	d.InstanceType = flags.String("aliyunecs-instance-type")
+	d.InstanceTypeAuto = flags.Bool("aliyunecs-instance-type-auto")
+	d.MinCPU = flags.Int("aliyunecs-min-cpu")
+	d.MinMemory = flags.Int("aliyunecs-min-memory")
 	d.VpcId = flags.String("aliyunecs-vpc-id")
 	d.VSwitchId = flags.String("aliyunecs-vswitch-id")
+	d.CreateVPC = flags.Bool("aliyunecs-create-vpc")
 	d.SecurityGroupName = flags.String("aliyunecs-security-group")
+	d.SecurityGroupId = flags.String("aliyunecs-security-group-id")
+	d.ExistingSecurityGroup = d.SecurityGroupId != ""
+	d.OpenPorts = flags.StringSlice("aliyunecs-open-port")
+	d.SourceCidr = flags.String("aliyunecs-source-cidr")
 	d.Zone = flags.String("aliyunecs-zone")
 	d.SwarmMaster = flags.Bool("swarm-master")
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
 	d.SSHUser = defaultSSHUser
 	d.SSHPassword = flags.String("aliyunecs-ssh-password")
+	d.KeyPairName = flags.String("aliyunecs-keypair-name")
+	d.SSHPrivateKeyPath = flags.String("aliyunecs-ssh-keypath")
+	if (d.KeyPairName == "") != (d.SSHPrivateKeyPath == "") {
+		return fmt.Errorf("%s | The --aliyunecs-keypair-name and --aliyunecs-ssh-keypath must be specified together", d.MachineName)
+	}
+	d.ExistingKeyPair = d.KeyPairName != ""
 	d.SSHPort = 22
+	d.SSHBastion = flags.String("aliyunecs-ssh-bastion")
+	if d.SSHBastion != "" {
+		if _, err := ssh.ParseBastion(d.SSHBastion); err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-ssh-bastion: %v", d.MachineName, err)
+		}
+	}
 	d.PrivateIPOnly = flags.Bool("aliyunecs-private-address-only")
+	d.EipId = flags.String("aliyunecs-eip-id")
+	d.EipAddress = flags.String("aliyunecs-eip-address")
+	if d.EipId != "" && d.EipAddress != "" {
+		return fmt.Errorf("%s | The --aliyunecs-eip-id and --aliyunecs-eip-address can not be specified together", d.MachineName)
+	}
+	d.ExistingEip = d.EipId != "" || d.EipAddress != ""
 	d.InternetMaxBandwidthOut = flags.Int("aliyunecs-internet-max-bandwidth")
+	d.InternetChargeType = common.InternetChargeType(flags.String("aliyunecs-internet-charge-type"))
+	d.InstanceChargeType = InstanceChargeType(flags.String("aliyunecs-instance-charge-type"))
+	d.Period = flags.Int("aliyunecs-period")
+	d.AutoRenew = flags.Bool("aliyunecs-auto-renew")
+	if err := d.setSpotFromFlags(flags.String("aliyunecs-spot-strategy"), flags.String("aliyunecs-spot-price-limit")); err != nil {
+		return err
+	}
+	d.RamRoleName = flags.String("aliyunecs-ram-role-name")
+	d.DeploymentSetId = flags.String("aliyunecs-deployment-set-id")
+	d.SwarmDeploymentSet = flags.Bool("aliyunecs-swarm-deployment-set")
+	if d.SwarmDeploymentSet {
+		if d.DeploymentSetId != "" {
+			return fmt.Errorf("%s | The --aliyunecs-swarm-deployment-set can not be used with --aliyunecs-deployment-set-id", d.MachineName)
+		}
+		if d.SwarmDiscovery == "" {
+			return fmt.Errorf("%s | The --aliyunecs-swarm-deployment-set requires --swarm-discovery", d.MachineName)
+		}
+	}
 	d.RouteCIDR = flags.String("aliyunecs-route-cidr")
-	d.SLBID = flags.String("aliyunecs-slb-id")
+	d.RouteCIDRPool = flags.String("aliyunecs-route-cidr-pool")
+	d.RouteCIDRSize = flags.Int("aliyunecs-route-cidr-size")
+	d.SLBAttachments = nil
+	for _, spec := range flags.StringSlice("aliyunecs-slb-id") {
+		attachment, err := parseSLBAttachment(spec)
+		if err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-slb-id %q: %v", d.MachineName, spec, err)
+		}
+		d.SLBAttachments = append(d.SLBAttachments, attachment)
+	}
+	d.SLBID = ""
+	if len(d.SLBAttachments) > 0 {
+		d.SLBID = d.SLBAttachments[0].LoadBalancerId
+	}
+	d.SLBListeners = nil
+	for _, spec := range flags.StringSlice("aliyunecs-slb-listener") {
+		listener, err := parseSLBListener(spec)
+		if err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-slb-listener %q: %v", d.MachineName, spec, err)
+		}
+		d.SLBListeners = append(d.SLBListeners, listener)
+	}
+	d.SLBAPIEndpoint = flags.String("aliyunecs-slb-api-endpoint")
+	if d.SLBAPIEndpoint == "" {
+		d.SLBAPIEndpoint = d.APIEndpoint
+	}
 	d.DiskSize = flags.Int("aliyunecs-disk-size")
 	d.DiskCategory = ecs.DiskCategory(flags.String("aliyunecs-disk-category"))
+	d.DataDiskSnapshotId = flags.String("aliyunecs-data-disk-snapshot-id")
+	if err := d.setDataDisksFromFlags(flags.StringSlice("aliyunecs-data-disk")); err != nil {
+		return err
+	}
+	d.KeepDataDisks = flags.Bool("aliyunecs-keep-data-disk")
 	tags := flags.StringSlice("aliyunecs-tag")
 	d.UpgradeKernel = flags.Bool("aliyunecs-upgrade-kernel")
+	prepare := flags.String("aliyunecs-prepare")
+	if d.UpgradeKernel {
+		prepare += "," + prepareKernel
+	}
+	prepareSteps, err := parsePrepareSteps(prepare)
+	if err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-prepare: %v", d.MachineName, err)
+	}
+	d.PrepareSteps = prepareSteps
 
 	ioOptimized := strings.ToLower(flags.String("aliyunecs-io-optimized"))
 
 	d.IoOptimized = (ioOptimized == "true" || ioOptimized == "optimized")
 	d.Description = flags.String("aliyunecs-description")
+	d.KeepOnFailure = flags.Bool("aliyunecs-keep-on-failure")
+
+	userData, err := readUserData(flags.String("aliyunecs-userdata"))
+	if err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-userdata: %v", d.MachineName, err)
+	}
+	d.UserData = userData
 	d.SystemDiskCategory = ecs.DiskCategory(flags.String("aliyunecs-system-disk-category"))
 
 	if d.SystemDiskCategory == "" && d.IoOptimized {
 		d.SystemDiskCategory = ecs.DiskCategoryCloudSSD
 	}
 
-	tagMap := make(map[string]string)
-	if len(tags) > 0 {
-		for _, tag := range tags {
-			s := strings.Split(tag, "=")
-			if len(s) != 2 {
-				log.Infof("%s | Invalid tag for --aliyunecs-tag", tag)
-				return fmt.Errorf("%s | Invalid tag for --aliyunecs-tag", tag)
-			}
-			k := strings.TrimSpace(s[0])
-			v := strings.TrimSpace(s[1])
-			tagMap[k] = v
-		}
+	tagMap, err := parseTags(tags)
+	if err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-tag: %v", d.MachineName, err)
 	}
-
 	if len(tagMap) > 0 {
 		d.Tags = tagMap
 	}
@@ -339,21 +672,64 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		}
 	}
 
-	//TODO support PayByTraffic
-	if d.InternetMaxBandwidthOut < 0 || d.InternetMaxBandwidthOut > 100 {
-		return fmt.Errorf("%s | aliyunecs driver --aliyunecs-internet-max-bandwidth: The value should be in 1 ~ 100", d.MachineName)
+	if d.RouteCIDRPool != "" {
+		if d.RouteCIDR != "" {
+			return fmt.Errorf("%s | The --aliyunecs-route-cidr-pool can not be used with --aliyunecs-route-cidr", d.MachineName)
+		}
+		if d.VpcId == "" && !d.CreateVPC {
+			return fmt.Errorf("%s | The --aliyunecs-route-cidr-pool requires the instance in VPC", d.MachineName)
+		}
+		if d.RouteCIDRSize == 0 {
+			d.RouteCIDRSize = defaultRouteCIDRSize
+		}
+		if _, err := parseRouteCIDRPool(d.RouteCIDRPool, d.RouteCIDRSize); err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-route-cidr-pool: %v", d.MachineName, err)
+		}
+	}
+
+	if d.SourceCidr == "" {
+		d.SourceCidr = ipRange
+	}
+	if _, _, err := net.ParseCIDR(d.SourceCidr); err != nil {
+		return fmt.Errorf("%s | Invalid CIDR value for --aliyunecs-source-cidr", d.MachineName)
+	}
+
+	for _, spec := range d.OpenPorts {
+		if _, err := parseOpenPort(spec); err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-open-port %q: %v", d.MachineName, spec, err)
+		}
+	}
+
+	if d.InternetMaxBandwidthOut < 0 || d.InternetMaxBandwidthOut > maxInternetBandwidth {
+		return fmt.Errorf("%s | aliyunecs driver --aliyunecs-internet-max-bandwidth: The value should be in 1 ~ %d", d.MachineName, maxInternetBandwidth)
 	}
 
 	if d.InternetMaxBandwidthOut == 0 {
 		d.InternetMaxBandwidthOut = 1
 	}
 
-	if d.AccessKey == "" {
-		return fmt.Errorf("%s | aliyunecs driver requires the --aliyunecs-access-key-id option", d.MachineName)
+	if err := d.validateChargeTypes(); err != nil {
+		return err
 	}
 
-	if d.SecretKey == "" {
-		return fmt.Errorf("%s | aliyunecs driver requires the --aliyunecs-access-key-secret option", d.MachineName)
+	if d.MinCPU < 0 || d.MinMemory < 0 {
+		return fmt.Errorf("%s | The --aliyunecs-min-cpu and --aliyunecs-min-memory should not be negative", d.MachineName)
+	}
+
+	if d.ImageID != "" && d.ImageName != "" {
+		return fmt.Errorf("%s | The --aliyunecs-image-id can not be used with --aliyunecs-image-name", d.MachineName)
+	}
+	if d.ImageName != "" {
+		if _, err := newImageMatcher(d.ImageName); err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-image-name %q: %v", d.MachineName, d.ImageName, err)
+		}
+	}
+	if _, err := validateImageOwner(d.ImageOwner); err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-image-owner %q: %v", d.MachineName, d.ImageOwner, err)
+	}
+
+	if err := d.setCredentialsFromFlags(); err != nil {
+		return err
 	}
 
 	//VpcId and VSwitchId are optional or required together
@@ -361,25 +737,49 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return fmt.Errorf("%s | aliyunecs driver requires both the --aliyunecs-vpc-id and --aliyunecs-vswitch-id for Virtual Private Cloud", d.MachineName)
 	}
 
-	if d.isSwarmMaster() {
-		u, err := url.Parse(d.SwarmHost)
-		if err != nil {
-			return fmt.Errorf("error parsing swarm host: %s", err)
-		}
+	if d.CreateVPC && d.VpcId != "" {
+		return fmt.Errorf("%s | The --aliyunecs-create-vpc can not be used with --aliyunecs-vpc-id and --aliyunecs-vswitch-id", d.MachineName)
+	}
 
-		parts := strings.Split(u.Host, ":")
-		port, err := strconv.Atoi(parts[1])
-		if err != nil {
+	if d.AdoptedInstance {
+		if err := d.validateAdoption(); err != nil {
 			return err
 		}
+	}
 
-		swarmPort = port
+	if err := d.parseSwarmPort(); err != nil {
+		return err
 	}
 
-	if d.APIEndpoint != "" {
-		if d.SLBID != "" {
-			return fmt.Errorf("Unsupport 'aliyunecs-slb-id' flag when the custom API endpoint is specified")
+	if len(d.SLBListeners) > 0 && len(d.SLBAttachments) == 0 {
+		return fmt.Errorf("%s | The --aliyunecs-slb-listener requires --aliyunecs-slb-id", d.MachineName)
+	}
+	return nil
+}
+
+func (d *Driver) validateChargeTypes() error {
+	switch d.InternetChargeType {
+	case "":
+		d.InternetChargeType = common.PayByTraffic
+	case common.PayByTraffic, common.PayByBandwidth:
+	default:
+		return fmt.Errorf("%s | Invalid --aliyunecs-internet-charge-type %q: The value should be PayByTraffic or PayByBandwidth", d.MachineName, d.InternetChargeType)
+	}
+
+	switch d.InstanceChargeType {
+	case "":
+		d.InstanceChargeType = PostPaid
+		fallthrough
+	case PostPaid:
+		if d.Period != 0 || d.AutoRenew {
+			return fmt.Errorf("%s | The --aliyunecs-period and --aliyunecs-auto-renew are only valid for PrePaid instance", d.MachineName)
 		}
+	case PrePaid:
+		if !isValidPeriod(d.Period) {
+			return fmt.Errorf("%s | Invalid --aliyunecs-period %d: The value should be in 1 ~ 9, 12, 24 or 36 months for PrePaid instance", d.MachineName, d.Period)
+		}
+	default:
+		return fmt.Errorf("%s | Invalid --aliyunecs-instance-charge-type %q: The value should be PostPaid or PrePaid", d.MachineName, d.InstanceChargeType)
 	}
 	return nil
 }
@@ -390,88 +790,174 @@ func (d *Driver) DriverName() string {
 
 func (d *Driver) checkPrereqs() error {
 
-	if d.SLBID != "" {
-		loadBalancer, err := d.getSLBClient().DescribeLoadBalancerAttribute(d.SLBID)
+	if err := d.checkRegion(); err != nil {
+		return err
+	}
+
+	if err := d.checkKeyPair(); err != nil {
+		return err
+	}
+
+	if err := d.checkEip(); err != nil {
+		return err
+	}
+
+	if err := d.checkSLBs(); err != nil {
+		return err
+	}
+
+	if err := d.checkDeploymentSet(); err != nil {
+		return err
+	}
+
+	if d.InstanceId == "" && len(d.ImportTags) > 0 {
+		instanceId, err := d.findInstanceByTags(d.ImportTags)
 		if err != nil {
-			return fmt.Errorf("%s | Invalid --aliyunecs-slb-id: %v", d.MachineName, err)
+			return err
+		}
+		d.InstanceId = instanceId
+	}
+
+	if !d.AdoptedInstance {
+		if err := d.checkInstanceType(); err != nil {
+			return err
+		}
+
+		imageID, err := d.resolveImageID()
+		if err != nil {
+			return err
+		}
+		// Record the resolved image to show what the instance is booted with
+		d.ImageID = imageID
+
+		d.ImageEngineInstalled, err = d.isEngineImage(imageID)
+		if err != nil {
+			log.Warnf("%s | Failed to describe tags of image %s, Docker will be installed: %v", d.MachineName, imageID, err)
+		}
+	}
+
+	if d.RamRoleName != "" {
+		if err := d.checkRamRole(); err != nil {
+			return err
+		}
+	}
+
+	if d.DataDiskSnapshotId != "" {
+		snapshot, err := d.getSnapshot(d.DataDiskSnapshotId)
+		if err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-data-disk-snapshot-id: %v", d.MachineName, err)
+		}
+		if snapshot.Progress != "100%" {
+			return fmt.Errorf("%s | Snapshot %s is not ready yet: %s", d.MachineName, snapshot.SnapshotId, snapshot.Progress)
+		}
+		if d.DiskSize > 0 && d.DiskSize < snapshot.SourceDiskSize {
+			return fmt.Errorf("%s | The --aliyunecs-disk-size should be no less than the size of snapshot %s (%d GB)", d.MachineName, snapshot.SnapshotId, snapshot.SourceDiskSize)
 		}
-		d.SLBIPAddress = loadBalancer.Address
 	}
 	return nil
 }
 
 func (d *Driver) PreCreateCheck() error {
-	return d.checkPrereqs()
+	if err := d.checkPrereqs(); err != nil {
+		return err
+	}
+	d.prereqsChecked = true
+	return nil
 }
 
 func (d *Driver) Create() error {
+	// The lookups of PreCreateCheck are not repeated, Create is called on the
+	// same driver right after it
+	if !d.prereqsChecked {
+		if err := d.checkPrereqs(); err != nil {
+			return err
+		}
+	}
+
+	d.rollback = &rollback{}
+	defer func() { d.rollback = nil }()
+
+	err := d.create()
+	if err == nil {
+		return nil
+	}
+
+	if d.KeepOnFailure {
+		log.Warnf("%s | Keeping the allocated resources of failed creation, remove them with 'docker-machine rm'", d.MachineName)
+		return err
+	}
+
+	if failed := d.rollback.run(d.MachineName); len(failed) > 0 {
+		log.Warnf("%s | Please release the following resources manually: %s", d.MachineName, strings.Join(failed, ", "))
+	}
+	return err
+}
+
+func (d *Driver) create() error {
 
 	var (
 		err error
 	)
-	VpcId := d.VpcId
-	VSwitchId := d.VSwitchId
 
-	if err := d.checkPrereqs(); err != nil {
-		return err
+	if d.CreateVPC {
+		log.Infof("%s | Configuring VPC for instance ...", d.MachineName)
+		if err := d.configureVPC(); err != nil {
+			return err
+		}
 	}
+
+	VpcId := d.VpcId
+	VSwitchId := d.VSwitchId
 	log.Infof("%s | Creating key pair for instance ...", d.MachineName)
 
 	if err := d.createKeyPair(); err != nil {
 		return fmt.Errorf("%s | Failed to create key pair: %v", d.MachineName, err)
 	}
 
+	if d.AdoptedInstance {
+		return d.adoptInstance()
+	}
+
+	if err := d.importMachineKeyPair(); err != nil {
+		return err
+	}
+
 	log.Infof("%s | Configuring security groups instance ...", d.MachineName)
 	if err := d.configureSecurityGroup(VpcId, d.SecurityGroupName); err != nil {
 		return err
 	}
 
-	// TODO Support data disk
-	if d.SSHPassword == "" {
-		d.SSHPassword = randomPassword()
-		log.Infof("%s | Launching instance with generated password, please update password in console or log in with ssh key.", d.MachineName)
+	if err := d.configureDeploymentSet(); err != nil {
+		return err
 	}
 
-	imageID := d.GetImageID(d.ImageID)
-	log.Infof("%s | Creating instance with image %s ...", d.MachineName, imageID)
+	log.Infof("%s | Creating instance with image %s ...", d.MachineName, d.ImageID)
 
-	ioOptimized := ecs.IoOptimizedNone
-	if d.IoOptimized {
-		ioOptimized = ecs.IoOptimizedOptimized
+	client, err := d.getClient()
+	if err != nil {
+		return err
 	}
-
 	args := ecs.CreateInstanceArgs{
 		RegionId:           d.Region,
 		InstanceName:       d.GetMachineName(),
 		Description:        d.Description,
-		ImageId:            imageID,
+		ImageId:            d.ImageID,
 		InstanceType:       d.InstanceType,
 		SecurityGroupId:    d.SecurityGroupId,
-		InternetChargeType: internetChargeType,
+		InternetChargeType: d.InternetChargeType,
 		Password:           d.SSHPassword,
 		VSwitchId:          VSwitchId,
 		ZoneId:             d.Zone,
-		IoOptimized:        ioOptimized,
-		ClientToken:        d.getClient().GenerateClientToken(),
+		IoOptimized:        d.ioOptimized(),
+		ClientToken:        client.GenerateClientToken(),
 	}
 
 	if d.SystemDiskCategory != "" {
 		args.SystemDisk.Category = d.SystemDiskCategory
 	}
 
-	if d.DiskSize > 0 { // Allocate Data Disk
-
-		disk := ecs.DataDiskType{
-			DiskName:           d.MachineName + "_data",
-			Description:        "Data volume for Docker",
-			Size:               d.DiskSize,
-			Category:           d.DiskCategory,
-			Device:             "/dev/xvdb",
-			DeleteWithInstance: true,
-		}
-
-		args.DataDisk = []ecs.DataDiskType{disk}
-
+	if len(d.DataDisks) > 0 {
+		args.DataDisk = d.dataDiskArgs()
 	}
 
 	// Set InternetMaxBandwidthOut only for classic network
@@ -479,8 +965,38 @@ func (d *Driver) Create() error {
 		args.InternetMaxBandwidthOut = d.InternetMaxBandwidthOut
 	}
 
+	createArgs := createInstanceArgs{
+		CreateInstanceArgs: args,
+		InstanceChargeType: d.InstanceChargeType,
+	}
+
+	// The password is not set unless it is given, the instance is logged in
+	// with the key pair
+	createArgs.KeyPairName = d.KeyPairName
+
+	if d.UserData != "" {
+		createArgs.UserData = base64.StdEncoding.EncodeToString([]byte(d.UserData))
+	}
+
+	if d.InstanceChargeType == PrePaid {
+		createArgs.Period = d.Period
+		createArgs.AutoRenew = &d.AutoRenew
+	}
+
+	d.setSpotArgs(&createArgs)
+
+	if d.RamRoleName != "" {
+		createArgs.RamRoleName = d.RamRoleName
+		log.Infof("%s | Creating instance with RAM role %s", d.MachineName, d.RamRoleName)
+	}
+
+	if d.DeploymentSetId != "" {
+		createArgs.DeploymentSetId = d.DeploymentSetId
+		log.Infof("%s | Creating instance in deployment set %s", d.MachineName, d.DeploymentSetId)
+	}
+
 	// Create instance
-	instanceId, err := d.getClient().CreateInstance(&args)
+	instanceId, err := d.createInstance(&createArgs)
 
 	if err != nil {
 		err = fmt.Errorf("%s | Failed to create instance: %s", d.MachineName, err)
@@ -490,9 +1006,23 @@ func (d *Driver) Create() error {
 	log.Infof("%s | Create instance %s successfully", d.MachineName, instanceId)
 
 	d.InstanceId = instanceId
+	d.rollback.add("instance "+instanceId, func() error {
+		if err := d.deleteInstance(instanceId); err != nil {
+			return err
+		}
+		// The PrePaid instance is left stopped with its key pair and
+		// security group to be removed with 'docker-machine rm'
+		if d.InstanceChargeType == PrePaid {
+			return errKept
+		}
+		d.InstanceId = ""
+		d.IPAddress = ""
+		d.PrivateIPAddress = ""
+		return nil
+	})
 
 	// Wait for creation successfully
-	err = d.getClient().WaitForInstance(instanceId, ecs.Stopped, timeout)
+	err = client.WaitForInstance(instanceId, ecs.Stopped, timeout)
 
 	if err != nil {
 		err = fmt.Errorf("%s | Failed to wait instance to 'stopped': %s", d.MachineName, err)
@@ -506,10 +1036,10 @@ func (d *Driver) Create() error {
 	if err == nil {
 		// Start instance
 		log.Infof("%s | Starting instance %s ...", d.MachineName, instanceId)
-		err = d.getClient().StartInstance(instanceId)
+		err = client.StartInstance(instanceId)
 		if err == nil {
 			// Wait for running
-			err = d.getClient().WaitForInstance(instanceId, ecs.Running, timeout)
+			err = client.WaitForInstance(instanceId, ecs.Running, timeout)
 			if err == nil {
 				log.Infof("%s | Start instance %s successfully", d.MachineName, instanceId)
 				instance, err := d.getInstance()
@@ -518,11 +1048,19 @@ func (d *Driver) Create() error {
 					d.Zone = instance.ZoneId
 					d.PrivateIPAddress = d.GetPrivateIP(instance)
 
+					if len(d.DataDisks) > 0 {
+						if err := d.recordDataDisks(); err != nil {
+							log.Warnf("%s | Failed to describe data disks of instance %s: %v", d.MachineName, instanceId, err)
+						}
+					}
+
 					d.IPAddress = d.getIP(instance)
 
 					ssh.SetDefaultClient(ssh.Native)
 
-					d.uploadKeyPair()
+					if err := d.setupInstance(); err != nil {
+						log.Warnf("%s | Failed to set up instance %s: %v", d.MachineName, instanceId, err)
+					}
 
 					log.Infof("%s | Created instance %s successfully with public IP address %s and private IP address %s",
 						d.MachineName,
@@ -539,38 +1077,28 @@ func (d *Driver) Create() error {
 		}
 	}
 
-	// Add instance tags
-	if len(d.Tags) > 0 {
-		log.Infof("%s | Adding tags %v to instance %s ...", d.MachineName, d.Tags, instanceId)
-		args := ecs.AddTagsArgs{
-			RegionId:     d.Region,
-			ResourceId:   instanceId,
-			ResourceType: ecs.TagResourceInstance,
-			Tag:          d.Tags,
-		}
-		err2 := d.getClient().AddTags(&args)
-		if err2 != nil {
-			log.Warnf("%s | Failed to add tags %v to instance %s: %v", d.MachineName, d.Tags, instanceId, err)
-		}
+	if err == nil {
+		d.tagMachine()
 	}
 
 	return err
 }
 
 func (d *Driver) configNetwork(vpcId string, instanceId string) error {
-	var err error
 	if vpcId == "" {
 		// Assign public IP if not private IP only
 
 		if !d.PrivateIPOnly {
 			// Allocate public IP address for classic network
-			var ipAddress string
-			ipAddress, err = d.getClient().AllocatePublicIpAddress(instanceId)
+			client, err := d.getClient()
 			if err != nil {
-				err = fmt.Errorf("%s | Error allocate public IP address for instance %s: %v", d.MachineName, instanceId, err)
-			} else {
-				log.Infof("%s | Allocate publice IP address %s for instance %s successfully", d.MachineName, ipAddress, instanceId)
+				return err
 			}
+			ipAddress, err := client.AllocatePublicIpAddress(instanceId)
+			if err != nil {
+				return fmt.Errorf("%s | Error allocate public IP address for instance %s: %v", d.MachineName, instanceId, err)
+			}
+			log.Infof("%s | Allocate publice IP address %s for instance %s successfully", d.MachineName, ipAddress, instanceId)
 		}
 	} else {
 		err := d.addRouteEntry(vpcId)
@@ -578,69 +1106,22 @@ func (d *Driver) configNetwork(vpcId string, instanceId string) error {
 			return err
 		}
 		if !d.PrivateIPOnly {
-			// Create EIP for virtual private cloud
-			eipArgs := ecs.AllocateEipAddressArgs{
-				RegionId:    d.Region,
-				Bandwidth:   d.InternetMaxBandwidthOut,
-				ClientToken: d.getClient().GenerateClientToken(),
-			}
-			log.Infof("%s | Allocating Eip address for instance %s ...", d.MachineName, instanceId)
-
-			_, allocationId, err := d.getClient().AllocateEipAddress(&eipArgs)
-			if err != nil {
-				return fmt.Errorf("%s | Failed to allocate EIP address: %v", d.MachineName, err)
-			}
-			err = d.getClient().WaitForEip(d.Region, allocationId, ecs.EipStatusAvailable, 60)
-			if err != nil {
-				log.Infof("%s | Releasing Eip address %s for ...", d.MachineName, allocationId)
-				err2 := d.getClient().ReleaseEipAddress(allocationId)
-				if err2 != nil {
-					log.Warnf("%s | Failed to release EIP address: %v", d.MachineName, err2)
-				}
-				return fmt.Errorf("%s | Failed to wait EIP %s: %v", d.MachineName, allocationId, err)
-			}
-			log.Infof("%s | Associating Eip address %s for instance %s ...", d.MachineName, allocationId, instanceId)
-			err = d.getClient().AssociateEipAddress(allocationId, instanceId)
-			if err != nil {
-				return fmt.Errorf("%s | Failed to associate EIP address: %v", d.MachineName, err)
-			}
-			err = d.getClient().WaitForEip(d.Region, allocationId, ecs.EipStatusInUse, 60)
-			if err != nil {
-				return fmt.Errorf("%s | Failed to wait EIP %s: %v", d.MachineName, allocationId, err)
+			// Associate EIP for virtual private cloud
+			if err := d.configureEip(instanceId); err != nil {
+				return err
 			}
 		}
 	}
 
-	if d.SLBID != "" { // Add the instance to SLB
-		log.Infof("%s | Adding instance %s to SLB %s ...", d.MachineName, instanceId, d.SLBID)
-		count := 0
-		for {
-			backendServers := []slb.BackendServerType{
-				slb.BackendServerType{
-					ServerId: instanceId,
-					Weight:   100,
-				},
-			}
-			_, err = d.getSLBClient().AddBackendServers(d.SLBID, backendServers)
-			if err != nil {
-				log.Errorf("%s | Failed to add instance to SLB: %v", d.MachineName, err)
-				count++
-				if count <= maxRetry {
-					time.Sleep(time.Duration(5000+mrand.Int63n(2000)) * time.Millisecond)
-					continue
-				} else {
-					return fmt.Errorf("%s | Failed to delete route entry after %d times", d.MachineName, maxRetry)
-				}
-			}
-			break
-		}
-	}
-	return nil
+	return d.attachSLBs(instanceId)
 }
 
 func (d *Driver) removeRouteEntry(vpcId string, regionId common.Region, instanceId string) error {
 
-	client := d.getClient()
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
 
 	describeArgs := ecs.DescribeVpcsArgs{
 		VpcId:    vpcId,
@@ -697,8 +1178,11 @@ func (d *Driver) removeRouteEntry(vpcId string, regionId common.Region, instance
 
 func (d *Driver) addRouteEntry(vpcId string) error {
 
-	if d.RouteCIDR != "" {
-		client := d.getClient()
+	if d.RouteCIDR != "" || d.RouteCIDRPool != "" {
+		client, err := d.getClient()
+		if err != nil {
+			return err
+		}
 
 		describeArgs := ecs.DescribeVpcsArgs{
 			VpcId:    vpcId,
@@ -719,8 +1203,16 @@ func (d *Driver) addRouteEntry(vpcId string) error {
 		}
 		routeTableId := vrouters[0].RouteTableIds.RouteTableId[0]
 		count := 0
+		// The subnets of the pool failed to route for being taken
+		taken := []*net.IPNet{}
 
 		for {
+			if d.RouteCIDRPool != "" {
+				if err := d.allocateRouteCIDR(vrouterId, taken); err != nil {
+					return err
+				}
+			}
+
 			createArgs := ecs.CreateRouteEntryArgs{
 				RouteTableId:         routeTableId,
 				DestinationCidrBlock: d.RouteCIDR,
@@ -729,11 +1221,32 @@ func (d *Driver) addRouteEntry(vpcId string) error {
 				ClientToken:          client.GenerateClientToken(),
 			}
 			err = client.CreateRouteEntry(&createArgs)
-			if err == nil {
+			created := err == nil
+			ecsErr, _ := err.(*common.Error)
+			conflicted := ecsErr != nil && d.RouteCIDRPool != "" && ecsErr.Code == routeEntryConflictCode
+			if conflicted {
+				// The entry may be created for the instance by the request
+				// failed before, check who routes the subnet then
+				nextHopId, err := d.routeEntryNextHop(vrouterId, d.RouteCIDR)
+				if err != nil {
+					return fmt.Errorf("%s | Failed to describe route entry of %s: %v", d.MachineName, d.RouteCIDR, err)
+				}
+				created = nextHopId == d.InstanceId
+			}
+			if created {
+				instanceId := d.InstanceId
+				d.rollback.add("route entry "+d.RouteCIDR, func() error {
+					if err := d.removeRouteEntry(vpcId, d.Region, instanceId); err != nil {
+						return err
+					}
+					if d.RouteCIDRPool != "" {
+						d.RouteCIDR = ""
+					}
+					return nil
+				})
 				break
 			}
 
-			ecsErr, _ := err.(*common.Error)
 			//Retry for IncorretRouteEntryStatus or Internal Error
 			if ecsErr != nil && (ecsErr.StatusCode == 500 || (ecsErr.StatusCode == 400 && ecsErr.Code == "IncorrectRouteEntryStatus")) {
 				count++
@@ -743,12 +1256,30 @@ func (d *Driver) addRouteEntry(vpcId string) error {
 				}
 
 			}
+			// The subnet is taken by the machine created in parallel, allocate
+			// another one from the pool even if the route table does not
+			// show the entry yet
+			if conflicted {
+				count++
+				if count <= maxRetry {
+					log.Infof("%s | Route CIDR %s is taken, allocating another one ...", d.MachineName, d.RouteCIDR)
+					if _, subnet, err := net.ParseCIDR(d.RouteCIDR); err == nil {
+						taken = append(taken, subnet)
+					}
+					continue
+				}
+			}
 			return fmt.Errorf("%s | Failed to create route entry: %v", d.MachineName, err)
 		}
 	}
 	return nil
 }
 
+// GetSSHBastion returns the bastion to reach the instance through
+func (d *Driver) GetSSHBastion() (string, error) {
+	return d.SSHBastion, nil
+}
+
 func (d *Driver) GetURL() (string, error) {
 	ip, err := d.GetIP()
 	if err != nil {
@@ -794,10 +1325,16 @@ func (d *Driver) getIP(inst *ecs.InstanceAttributesType) string {
 }
 
 func (d *Driver) GetState() (state.State, error) {
-	inst, err := d.getInstance()
+	inst, err := d.describeInstanceAttribute(d.InstanceId)
 	if err != nil {
+		if d.isReleased(err) {
+			return state.Reclaimed, nil
+		}
 		return state.Error, err
 	}
+	if inst.isRecycling() {
+		return state.Reclaimed, nil
+	}
 	switch ecs.InstanceStatus(inst.Status) {
 	case ecs.Starting:
 		return state.Starting, nil
@@ -817,13 +1354,23 @@ func (d *Driver) GetSSHHostname() (string, error) {
 }
 
 func (d *Driver) Start() error {
-	if err := d.getClient().StartInstance(d.InstanceId); err != nil {
+	if d.isSpot() {
+		if s, err := d.GetState(); err == nil && s == state.Reclaimed {
+			return fmt.Errorf("%s | Spot instance %s has been reclaimed by Aliyun and can not be started, please remove the machine and create it again", d.MachineName, d.InstanceId)
+		}
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	if err := client.StartInstance(d.InstanceId); err != nil {
 		log.Errorf("%s | Failed to start instance %s: %v", d.MachineName, d.InstanceId, err)
 		return err
 	}
 
 	// Wait for running
-	err := d.getClient().WaitForInstance(d.InstanceId, ecs.Running, timeout)
+	err = client.WaitForInstance(d.InstanceId, ecs.Running, timeout)
 
 	if err != nil {
 		log.Errorf("%s | Failed to wait instance %s running: %v", d.MachineName, d.InstanceId, err)
@@ -834,13 +1381,17 @@ func (d *Driver) Start() error {
 }
 
 func (d *Driver) Stop() error {
-	if err := d.getClient().StopInstance(d.InstanceId, false); err != nil {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	if err := client.StopInstance(d.InstanceId, false); err != nil {
 		log.Errorf("%s | Failed to stop instance %s: %v", d.MachineName, d.InstanceId, err)
 		return err
 	}
 
 	// Wait for stopped
-	err := d.getClient().WaitForInstance(d.InstanceId, ecs.Stopped, timeout)
+	err = client.WaitForInstance(d.InstanceId, ecs.Stopped, timeout)
 
 	if err != nil {
 		log.Errorf("%s | Failed to wait instance %s stopped: %v", d.MachineName, d.InstanceId, err)
@@ -857,6 +1408,14 @@ func (d *Driver) Remove() error {
 		return fmt.Errorf("%s | Unknown instance id", d.MachineName)
 	}
 
+	if d.AdoptedInstance && !d.DeleteAdoptedInstance {
+		log.Infof("%s | Detaching adopted instance %s, it is left as it is", d.MachineName, d.InstanceId)
+		d.InstanceId = ""
+		return nil
+	}
+
+	d.detachSLBs()
+
 	s, err := d.GetState()
 	if err == nil && s == state.Running {
 		if err := d.Stop(); err != nil {
@@ -864,27 +1423,13 @@ func (d *Driver) Remove() error {
 		}
 	}
 
-	instance, err := d.getInstance()
+	instance, err := d.describeInstanceAttribute(d.InstanceId)
 	if err != nil {
 		log.Errorf("%s | Unable to describe the instance %s: %s", d.MachineName, d.InstanceId, err)
 	} else {
 		// Check and release EIP if exists
-		if len(instance.EipAddress.AllocationId) != 0 {
-
-			allocationId := instance.EipAddress.AllocationId
-
-			err = d.getClient().UnassociateEipAddress(allocationId, instance.InstanceId)
-			if err != nil {
-				log.Errorf("%s | Failed to unassociate EIP address from instance %s: %v", d.MachineName, d.InstanceId, err)
-			}
-			err = d.getClient().WaitForEip(instance.RegionId, allocationId, ecs.EipStatusAvailable, 0)
-			if err != nil {
-				log.Errorf("%s | Failed to wait EIP %s available: %v", d.MachineName, allocationId, err)
-			}
-			err = d.getClient().ReleaseEipAddress(allocationId)
-			if err != nil {
-				log.Errorf("%s | Failed to release EIP address: %v", d.MachineName, err)
-			}
+		if allocationId := instance.EipAddress.AllocationId; allocationId != "" {
+			d.removeEip(allocationId, instance.InstanceId)
 		}
 		log.Debugf("%s | instance.VpcAttributes: %++v\n", d.MachineName, instance.VpcAttributes)
 
@@ -895,11 +1440,56 @@ func (d *Driver) Remove() error {
 		}
 	}
 
-	log.Infof("%s | Deleting instance: %s", d.MachineName, d.InstanceId)
-	if err := d.getClient().DeleteInstance(d.InstanceId); err != nil {
-		return fmt.Errorf("%s | Unable to delete instance %s: %s", d.MachineName, d.InstanceId, err)
+	if d.InstanceChargeType == PrePaid {
+		// PrePaid instance can not be deleted until it expires, so just
+		// leave it stopped and stop the renewal
+		if d.AutoRenew {
+			log.Infof("%s | Disabling auto renewal of PrePaid instance %s ...", d.MachineName, d.InstanceId)
+			if err := d.modifyInstanceAutoRenewAttribute(d.InstanceId, false); err != nil {
+				return fmt.Errorf("%s | Unable to disable auto renewal of instance %s: %s", d.MachineName, d.InstanceId, err)
+			}
+		}
+		log.Warnf("%s | PrePaid instance %s can not be deleted, it is stopped and will be released by Aliyun on expiration", d.MachineName, d.InstanceId)
+	} else {
+		if d.KeepDataDisks {
+			d.keepDataDisks()
+		}
+
+		log.Infof("%s | Deleting instance: %s", d.MachineName, d.InstanceId)
+		client, err := d.getClient()
+		if err != nil {
+			return err
+		}
+		if err := client.DeleteInstance(d.InstanceId); d.isReleased(err) {
+			log.Infof("%s | Spot instance %s has been released by Aliyun already", d.MachineName, d.InstanceId)
+		} else if err != nil {
+			return fmt.Errorf("%s | Unable to delete instance %s: %s", d.MachineName, d.InstanceId, err)
+		}
+
+		if err := d.cleanupSecurityGroup(d.InstanceId); err != nil {
+			log.Warnf("%s | Failed to clean up security group %s: %v", d.MachineName, d.SecurityGroupId, err)
+		}
+
+		if d.CreateVPC && d.VSwitchId != "" {
+			if err := d.cleanupVPC(d.InstanceId); err != nil {
+				log.Warnf("%s | Failed to clean up VPC %s: %v", d.MachineName, d.VpcId, err)
+			}
+		}
+
+		if err := d.cleanupDeploymentSet(d.InstanceId); err != nil {
+			log.Warnf("%s | Failed to clean up deployment set %s: %v", d.MachineName, d.DeploymentSetId, err)
+		}
+
+		// The key pair is kept with the PrePaid instance to log in with
+		d.removeKeyPair()
+	}
+
+	// The subnet is allocated again if the machine is created again
+	if d.RouteCIDRPool != "" {
+		d.RouteCIDR = ""
 	}
 	d.InstanceId = ""
+	d.DataDiskId = ""
 	d.IPAddress = ""
 	d.PrivateIPAddress = ""
 	d.Zone = ""
@@ -907,7 +1497,11 @@ func (d *Driver) Remove() error {
 }
 
 func (d *Driver) Restart() error {
-	if err := d.getClient().RebootInstance(d.InstanceId, false); err != nil {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	if err := client.RebootInstance(d.InstanceId, false); err != nil {
 		return fmt.Errorf("%s | Unable to restart instance %s: %s", d.MachineName, d.InstanceId, err)
 	}
 	return nil
@@ -916,52 +1510,65 @@ func (d *Driver) Restart() error {
 func (d *Driver) Kill() error {
 	log.Debugf("%s | Killing instance ...", d.MachineName)
 
-	if err := d.getClient().StopInstance(d.InstanceId, true); err != nil {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	if err := client.StopInstance(d.InstanceId, true); err != nil {
 		return fmt.Errorf("%s | Unable to kill instance %s: %s", d.MachineName, d.InstanceId, err)
 	}
 	return nil
 }
 
-func (d *Driver) getSLBClient() *slb.Client {
+func (d *Driver) getSLBClient() (*slb.Client, error) {
 	if d.slbClient == nil {
-		client := slb.NewClient(d.AccessKey, d.SecretKey)
+		creds, err := d.credentials()
+		if err != nil {
+			return nil, err
+		}
+		client := slb.NewClient(creds.AccessKeyId, creds.AccessKeySecret)
+		client.SetSecurityToken(creds.SecurityToken)
+		if d.SLBAPIEndpoint != "" {
+			client.SetEndpoint(d.SLBAPIEndpoint)
+		}
 		client.SetDebug(false)
 		d.slbClient = client
 	}
-	return d.slbClient
+	return d.slbClient, nil
 }
 
-func (d *Driver) getClient() *ecs.Client {
+func (d *Driver) getClient() (*ecs.Client, error) {
 	if d.client == nil {
-		client := ecs.NewClient(d.AccessKey, d.SecretKey)
+		creds, err := d.credentials()
+		if err != nil {
+			return nil, err
+		}
+		client := ecs.NewClient(creds.AccessKeyId, creds.AccessKeySecret)
+		client.SetSecurityToken(creds.SecurityToken)
 		if d.APIEndpoint != "" {
 			client.SetEndpoint(d.APIEndpoint)
 		}
 		client.SetDebug(false)
 		d.client = client
 	}
-	return d.client
-}
-
-func (d *Driver) getInstance() (*ecs.InstanceAttributesType, error) {
-	return d.getClient().DescribeInstanceAttribute(d.InstanceId)
+	return d.client, nil
 }
 
-func (d *Driver) createKeyPair() error {
-
-	log.Debugf("%s | SSH key path: %s", d.MachineName, d.GetSSHKeyPath())
-
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+// invoke calls the ECS API which is not covered by the vendored aliyungo
+func (d *Driver) invoke(action string, args interface{}, response interface{}) error {
+	client, err := d.getClient()
+	if err != nil {
 		return err
 	}
+	return client.Invoke(action, args, response)
+}
 
-	publicKey, err := ioutil.ReadFile(d.GetSSHKeyPath() + ".pub")
+func (d *Driver) getInstance() (*ecs.InstanceAttributesType, error) {
+	client, err := d.getClient()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	d.PublicKey = publicKey
-	return nil
+	return client.DescribeInstanceAttribute(d.InstanceId)
 }
 
 func (d *Driver) isSwarmMaster() bool {
@@ -973,7 +1580,11 @@ func (d *Driver) getSecurityGroup(id string) (sg *ecs.DescribeSecurityGroupAttri
 		SecurityGroupId: id,
 		RegionId:        d.Region,
 	}
-	return d.getClient().DescribeSecurityGroupAttribute(&args)
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.DescribeSecurityGroupAttribute(&args)
 }
 
 func (d *Driver) securityGroupAvailableFunc(id string) func() bool {
@@ -992,13 +1603,41 @@ func (d *Driver) configureSecurityGroup(vpcId string, groupName string) error {
 
 	var securityGroup *ecs.DescribeSecurityGroupAttributeResponse
 
+	if d.ExistingSecurityGroup {
+		securityGroup, err := d.getSecurityGroup(d.SecurityGroupId)
+		if err != nil {
+			return fmt.Errorf("%s | Failed to describe security group %s: %v", d.MachineName, d.SecurityGroupId, err)
+		}
+		if securityGroup.VpcId != vpcId {
+			return fmt.Errorf("%s | Security group %s does not belong to VPC %q", d.MachineName, d.SecurityGroupId, vpcId)
+		}
+		log.Debugf("%s | Using existing security group %s", d.MachineName, d.SecurityGroupId)
+		return d.authorizeSecurityGroup(securityGroup)
+	}
+
+	// The rules of the group shared by the machines are only added, so the
+	// machine with restricted ingress gets a group of its own to revoke the
+	// rules opened to other sources
+	if d.isRestricted() {
+		securityGroup, err := d.createSecurityGroup(vpcId, fmt.Sprintf("%s-%s", groupName, d.MachineName))
+		if err != nil {
+			return err
+		}
+		d.MachineSecurityGroup = true
+		return d.authorizeSecurityGroup(securityGroup)
+	}
+
 	args := ecs.DescribeSecurityGroupsArgs{
 		RegionId: d.Region,
 		VpcId:    vpcId,
 	}
 
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
 	for {
-		groups, pagination, err := d.getClient().DescribeSecurityGroups(&args)
+		groups, pagination, err := client.DescribeSecurityGroups(&args)
 		if err != nil {
 			return err
 		}
@@ -1025,39 +1664,81 @@ func (d *Driver) configureSecurityGroup(vpcId string, groupName string) error {
 
 	// if not found, create
 	if securityGroup == nil {
-		log.Debugf("%s | Creating security group (%s) in %s", d.MachineName, groupName, d.VpcId)
-		creationArgs := ecs.CreateSecurityGroupArgs{
-			RegionId:          d.Region,
-			SecurityGroupName: groupName,
-			Description:       "Docker Machine",
-			VpcId:             vpcId,
-			ClientToken:       d.getClient().GenerateClientToken(),
-		}
-
-		groupId, err := d.getClient().CreateSecurityGroup(&creationArgs)
+		var err error
+		securityGroup, err = d.createSecurityGroup(vpcId, groupName)
 		if err != nil {
 			return err
 		}
+	}
 
-		// wait until created (dat eventual consistency)
-		log.Debugf("%s | Waiting for group (%s) to become available", d.MachineName, groupId)
-		if err := mcnutils.WaitFor(d.securityGroupAvailableFunc(groupId)); err != nil {
-			return err
+	return d.authorizeSecurityGroup(securityGroup)
+}
+
+// createSecurityGroup creates the security group in VPC and waits for it
+func (d *Driver) createSecurityGroup(vpcId string, groupName string) (*ecs.DescribeSecurityGroupAttributeResponse, error) {
+	log.Debugf("%s | Creating security group (%s) in %s", d.MachineName, groupName, d.VpcId)
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	creationArgs := ecs.CreateSecurityGroupArgs{
+		RegionId:          d.Region,
+		SecurityGroupName: groupName,
+		Description:       "Docker Machine",
+		VpcId:             vpcId,
+		ClientToken:       client.GenerateClientToken(),
+	}
+
+	groupId, err := client.CreateSecurityGroup(&creationArgs)
+	if err != nil {
+		return nil, err
+	}
+	d.rollback.add("security group "+groupId, func() error {
+		err := retry(func() error { return client.DeleteSecurityGroup(d.Region, groupId) })
+		if err == nil {
+			d.SecurityGroupId = ""
 		}
-		securityGroup, err = d.getSecurityGroup(groupId)
-		if err != nil {
-			return err
+		return err
+	})
+
+	if len(d.Tags) > 0 {
+		if err := d.addResourceTags(tagResourceSecurityGroup, groupId, d.Tags); err != nil {
+			log.Warnf("%s | Failed to add tags to security group %s: %v", d.MachineName, groupId, err)
 		}
 	}
 
+	// wait until created (dat eventual consistency)
+	log.Debugf("%s | Waiting for group (%s) to become available", d.MachineName, groupId)
+	if err := mcnutils.WaitFor(d.securityGroupAvailableFunc(groupId)); err != nil {
+		return nil, err
+	}
+	return d.getSecurityGroup(groupId)
+}
+
+func (d *Driver) authorizeSecurityGroup(securityGroup *ecs.DescribeSecurityGroupAttributeResponse) error {
 	d.SecurityGroupId = securityGroup.SecurityGroupId
 
+	// The rules of the security group shared with others are never revoked,
+	// only the missing ones are added then
+	if d.MachineSecurityGroup {
+		for _, permission := range d.stalePermissions(securityGroup) {
+			log.Debugf("%s | Revoking group %s with permission: %v", d.MachineName, securityGroup.SecurityGroupName, permission)
+			if err := d.revokeSecurityGroup(d.SecurityGroupId, permission); err != nil {
+				return err
+			}
+		}
+	}
+
 	perms := d.configureSecurityGroupPermissions(securityGroup)
 
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
 	for _, permission := range perms {
 		log.Debugf("%s | Authorizing group %s with permission: %v", d.MachineName, securityGroup.SecurityGroupName, permission)
 		args := permission.createAuthorizeSecurityGroupArgs(d.Region, d.SecurityGroupId)
-		if err := d.getClient().AuthorizeSecurityGroup(args); err != nil {
+		if err := client.AuthorizeSecurityGroup(args); err != nil {
 			return err
 		}
 
@@ -1079,71 +1760,25 @@ func (p *IpPermission) createAuthorizeSecurityGroupArgs(regionId common.Region,
 		SecurityGroupId: securityGroupId,
 		IpProtocol:      p.IpProtocol,
 		SourceCidrIp:    p.IpRange,
-		PortRange:       fmt.Sprintf("%d/%d", p.FromPort, p.ToPort),
+		PortRange:       p.portRange(),
 	}
 	return &args
 }
 
 func (d *Driver) configureSecurityGroupPermissions(group *ecs.DescribeSecurityGroupAttributeResponse) []IpPermission {
-	hasSSHPort := false
-	hasDockerPort := false
-	hasSwarmPort := false
-	hasAllIncomingPort := false
-	for _, p := range group.Permissions.Permission {
-		portRange := strings.Split(p.PortRange, "/")
-
-		log.Debugf("%s | portRange %v", d.MachineName, portRange)
-		fromPort, _ := strconv.Atoi(portRange[0])
-		switch fromPort {
-		case -1:
-			if portRange[1] == "-1" && p.IpProtocol == "ALL" && p.Policy == "Accept" {
-				hasAllIncomingPort = true
-			}
-		case 22:
-			hasSSHPort = true
-		case dockerPort:
-			hasDockerPort = true
-		case swarmPort:
-			hasSwarmPort = true
-		}
-	}
-
 	perms := []IpPermission{}
 
-	if !hasSSHPort {
-		perms = append(perms, IpPermission{
-			IpProtocol: ecs.IpProtocolTCP,
-			FromPort:   22,
-			ToPort:     22,
-			IpRange:    ipRange,
-		})
-	}
-
-	if !hasDockerPort {
-		perms = append(perms, IpPermission{
-			IpProtocol: ecs.IpProtocolTCP,
-			FromPort:   dockerPort,
-			ToPort:     dockerPort,
-			IpRange:    ipRange,
-		})
-	}
-
-	if !hasSwarmPort && d.SwarmMaster {
-		perms = append(perms, IpPermission{
-			IpProtocol: ecs.IpProtocolTCP,
-			FromPort:   swarmPort,
-			ToPort:     swarmPort,
-			IpRange:    ipRange,
-		})
-	}
-
-	if !hasAllIncomingPort {
-		perms = append(perms, IpPermission{
-			IpProtocol: ecs.IpProtocolAll,
-			FromPort:   -1,
-			ToPort:     -1,
-			IpRange:    ipRange,
-		})
+	for _, p := range d.desiredPermissions() {
+		found := false
+		for _, permission := range group.Permissions.Permission {
+			if p.matches(permission) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			perms = append(perms, p)
+		}
 	}
 
 	log.Debugf("%s | Configuring new permissions: %v", d.MachineName, perms)
@@ -1153,7 +1788,11 @@ func (d *Driver) configureSecurityGroupPermissions(group *ecs.DescribeSecurityGr
 
 func (d *Driver) deleteSecurityGroup() error {
 	log.Infof("%s | Deleting security group %s", d.MachineName, d.SecurityGroupId)
-	if err := d.getClient().DeleteSecurityGroup(d.Region, d.SecurityGroupId); err != nil {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteSecurityGroup(d.Region, d.SecurityGroupId); err != nil {
 		return err
 	}
 
@@ -1172,23 +1811,27 @@ func generateId() string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-func (d *Driver) uploadKeyPair() error {
+// uploadKeyPair uploads the public key with the password of SSH user
+func (d *Driver) uploadKeyPair() (ssh.Client, error) {
 	ipAddr := d.IPAddress
 	port, _ := d.GetSSHPort()
 	tcpAddr := fmt.Sprintf("%s:%d", ipAddr, port)
 
-	log.Infof("%s | Waiting SSH service %s is ready to connect ...", d.MachineName, tcpAddr)
-
 	log.Infof("%s | Uploading SSH keypair to %s ...", d.MachineName, tcpAddr)
 
 	auth := ssh.Auth{
 		Passwords: []string{d.SSHPassword},
 	}
 
-	sshClient, err := ssh.NewClient(d.GetSSHUsername(), ipAddr, port, &auth)
+	bastion, err := drivers.GetSSHBastion(d)
+	if err != nil {
+		return nil, err
+	}
+
+	sshClient, err := ssh.NewBastionClient(d.GetSSHUsername(), ipAddr, port, &auth, bastion)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	command := fmt.Sprintf("mkdir -p ~/.ssh; echo '%s' > ~/.ssh/authorized_keys", string(d.PublicKey))
@@ -1200,53 +1843,8 @@ func (d *Driver) uploadKeyPair() error {
 	log.Debugf("%s | Upload command err, output: %v: %s", d.MachineName, err, output)
 
 	if err != nil {
-		return err
-	}
-
-	log.Debugf("%s | Upload the public key with command: %s", d.MachineName, command)
-
-	d.fixRoutingRules(sshClient)
-
-	if d.DiskSize > 0 {
-		d.autoFdisk(sshClient)
-	}
-
-	if d.UpgradeKernel {
-		d.upgradeKernel(sshClient, tcpAddr)
+		return nil, err
 	}
 
-	return nil
-}
-
-// Fix the routing rules
-func (d *Driver) fixRoutingRules(sshClient ssh.Client) {
-	output, err := sshClient.Output("route del -net 172.16.0.0/12")
-	log.Debugf("%s | Delete route command err, output: %v: %s", d.MachineName, err, output)
-
-	output, err = sshClient.Output("if [ -e /etc/network/interfaces ]; then sed -i '/^up route add -net 172.16.0.0 netmask 255.240.0.0 gw/d' /etc/network/interfaces; fi")
-	log.Debugf("%s | Fix route in /etc/network/interfaces command err, output: %v: %s", d.MachineName, err, output)
-
-	output, err = sshClient.Output("if [ -e /etc/sysconfig/network-scripts/route-eth0 ]; then sed -i '/^172.16.0.0\\/12 via /d' /etc/sysconfig/network-scripts/route-eth0; fi")
-	log.Debugf("%s | Fix route in /etc/sysconfig/network-scripts/route-eth0 command err, output: %v: %s", d.MachineName, err, output)
-}
-
-// Mount the addtional disk
-func (d *Driver) autoFdisk(sshClient ssh.Client) {
-	script := fmt.Sprintf("cat > ~/machine_autofdisk.sh <<MACHINE_EOF\n%s\nMACHINE_EOF\n", autoFdiskScript)
-	output, err := sshClient.Output(script)
-	output, err = sshClient.Output("bash ~/machine_autofdisk.sh")
-	log.Debugf("%s | Auto Fdisk command err, output: %v: %s", d.MachineName, err, output)
-}
-
-// Install Kernel 3.19
-func (d *Driver) upgradeKernel(sshClient ssh.Client, tcpAddr string) {
-	log.Debugf("%s | Upgrade kernel version ...", d.MachineName)
-	output, err := sshClient.Output("for i in 1 2 3 4 5; do apt-get update -y && break || sleep 5; done")
-	log.Infof("%s | apt-get update update err, output: %v: %s", d.MachineName, err, output)
-	output, err = sshClient.Output("for i in 1 2 3 4 5; do apt-get install -y linux-generic-lts-vivid && break || sleep 5; done")
-	log.Infof("%s | Upgrade kernel err, output: %v: %s", d.MachineName, err, output)
-	time.Sleep(5 * time.Second)
-	log.Infof("%s | Restart VM instance for kernel update ...", d.MachineName)
-	d.Restart()
-	time.Sleep(30 * time.Second)
+	return sshClient, nil
 }
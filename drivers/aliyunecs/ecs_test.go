@@ -3,9 +3,11 @@ package aliyunecs
 import (
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
 )
 
 const (
@@ -34,7 +36,11 @@ func (d DriverOptionsMock) String(key string) string {
 }
 
 func (d DriverOptionsMock) StringSlice(key string) []string {
-	return d.Data[key].([]string)
+	v := d.Data[key]
+	if v == nil {
+		v = []string{}
+	}
+	return v.([]string)
 }
 
 func (d DriverOptionsMock) Int(key string) int {
@@ -77,6 +83,7 @@ func getDefaultTestDriverFlags() *DriverOptionsMock {
 			"swarm-discovery":             "",
 			"aliyunecs-region":            "cn-hangzhou",
 			"aliyunecs-image-id":          "img-12345",
+			"aliyunecs-instance-type":     defaultInstanceType,
 			"aliyunecs-access-key-id":     "abcdefg",
 			"aliyunecs-access-key-secret": "12345",
 			"aliyunecs-tag":               []string{"a=tag1", "b=tag2"},
@@ -234,3 +241,161 @@ func TestValidateECSRegionInvalid(t *testing.T) {
 	}
 
 }
+
+func TestSetConfigFromFlagsChargeTypes(t *testing.T) {
+	testCases := []struct {
+		flags map[string]interface{}
+		valid bool
+	}{
+		{map[string]interface{}{}, true},
+		{map[string]interface{}{"aliyunecs-internet-charge-type": "PayByBandwidth"}, true},
+		{map[string]interface{}{"aliyunecs-internet-charge-type": "PayByHour"}, false},
+		{map[string]interface{}{"aliyunecs-instance-charge-type": "PrePaid", "aliyunecs-period": 12, "aliyunecs-auto-renew": true}, true},
+		{map[string]interface{}{"aliyunecs-instance-charge-type": "PrePaid"}, false},
+		{map[string]interface{}{"aliyunecs-instance-charge-type": "PrePaid", "aliyunecs-period": 10}, false},
+		{map[string]interface{}{"aliyunecs-instance-charge-type": "PostPaid", "aliyunecs-period": 1}, false},
+		{map[string]interface{}{"aliyunecs-auto-renew": true}, false},
+		{map[string]interface{}{"aliyunecs-instance-charge-type": "Free"}, false},
+	}
+
+	for _, tc := range testCases {
+		flags := getDefaultTestDriverFlags()
+		for k, v := range tc.flags {
+			flags.Data[k] = v
+		}
+		d := NewDriver(machineTestName, "")
+		err := d.SetConfigFromFlags(flags)
+		if tc.valid && err != nil {
+			t.Fatalf("%v should be valid: %v", tc.flags, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("%v should be invalid", tc.flags)
+		}
+	}
+}
+
+func TestRemovePrePaidInstance(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+	d.InstanceChargeType = PrePaid
+	d.AutoRenew = true
+	d.KeyPairName = "docker-machine-test"
+
+	if err := d.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if !f.hasCalled("ModifyInstanceAutoRenewAttribute") {
+		t.Error("auto renewal of PrePaid instance should be disabled")
+	}
+	if f.hasCalled("DeleteInstance") {
+		t.Error("PrePaid instance should not be deleted")
+	}
+	if f.hasCalled("DeleteKeyPairs") {
+		t.Error("key pair of PrePaid instance should not be deleted")
+	}
+}
+
+func TestReadUserData(t *testing.T) {
+	userData, err := readUserData("#cloud-config\nruncmd: []")
+	if err != nil || userData != "#cloud-config\nruncmd: []" {
+		t.Fatalf("inline user data should be returned as it is: %q, %v", userData, err)
+	}
+
+	file, err := ioutil.TempFile("", "userdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("#!/bin/sh\necho hello")
+	file.Close()
+
+	userData, err = readUserData(file.Name())
+	if err != nil || userData != "#!/bin/sh\necho hello" {
+		t.Fatalf("user data should be read from file: %q, %v", userData, err)
+	}
+
+	if _, err := readUserData(strings.Repeat("x", maxUserDataSize)); err == nil {
+		t.Fatal("user data exceeding the size limit should be rejected")
+	}
+}
+
+func TestSetConfigFromFlagsAdoption(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-instance-id"] = "i-existing"
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	if err := d.SetConfigFromFlags(flags); err == nil {
+		t.Fatal("--aliyunecs-ssh-password should be required for adoption")
+	}
+
+	flags.Data["aliyunecs-ssh-password"] = "secret"
+	if err := d.SetConfigFromFlags(flags); err != nil {
+		t.Fatal(err)
+	}
+	if !d.AdoptedInstance || d.InstanceId != "i-existing" {
+		t.Fatal("instance should be adopted")
+	}
+
+	flags.Data["aliyunecs-disk-size"] = 100
+	if err := d.SetConfigFromFlags(flags); err == nil {
+		t.Fatal("data disk of adopted instance should not be specified")
+	}
+}
+
+func TestRemoveAdoptedInstance(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-existing"
+	d.AdoptedInstance = true
+
+	if err := d.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.called()) != 0 {
+		t.Errorf("adopted instance should be detached without API calls: %v", f.called())
+	}
+
+	d.InstanceId = "i-existing"
+	d.DeleteAdoptedInstance = true
+	if err := d.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if !f.hasCalled("DeleteInstance") {
+		t.Error("adopted instance should be deleted")
+	}
+}
+
+func TestSetConfigFromFlagsSSHBastion(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-private-address-only"] = true
+	flags.Data["aliyunecs-ssh-bastion"] = "admin@jump.example.com:2222"
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	if err := d.SetConfigFromFlags(flags); err != nil {
+		t.Fatal(err)
+	}
+
+	bastion, err := drivers.GetSSHBastion(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bastion.String() != "admin@jump.example.com:2222" {
+		t.Fatalf("unexpected bastion %s", bastion)
+	}
+
+	flags.Data["aliyunecs-ssh-bastion"] = "jump.example.com"
+	if err := d.SetConfigFromFlags(flags); err == nil {
+		t.Fatal("bastion without user should be invalid")
+	}
+}
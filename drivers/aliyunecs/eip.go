@@ -0,0 +1,136 @@
+package aliyunecs
+
+import (
+	"fmt"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// checkEip resolves the existing EIP of --aliyunecs-eip-id or
+// --aliyunecs-eip-address, which must be available to associate
+func (d *Driver) checkEip() error {
+	if !d.ExistingEip {
+		return nil
+	}
+
+	if d.VpcId == "" && !d.CreateVPC {
+		return fmt.Errorf("%s | The existing EIP can only be associated with the instance in VPC", d.MachineName)
+	}
+	if d.PrivateIPOnly {
+		return fmt.Errorf("%s | The existing EIP can not be associated with --aliyunecs-private-address-only", d.MachineName)
+	}
+
+	eip := d.EipId
+	if eip == "" {
+		eip = d.EipAddress
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	eips, _, err := client.DescribeEipAddresses(&ecs.DescribeEipAddressesArgs{
+		RegionId:     d.Region,
+		AllocationId: d.EipId,
+		EipAddress:   d.EipAddress,
+	})
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe EIP %s: %v", d.MachineName, eip, err)
+	}
+	if len(eips) == 0 {
+		return fmt.Errorf("%s | No such EIP %s in region %s", d.MachineName, eip, d.Region)
+	}
+	if eips[0].Status != ecs.EipStatusAvailable {
+		return fmt.Errorf("%s | The EIP %s is %s, it must be available to associate", d.MachineName, eip, eips[0].Status)
+	}
+
+	d.EipId = eips[0].AllocationId
+	d.EipAddress = eips[0].IpAddress
+	return nil
+}
+
+// ownsEip returns true if the EIP is allocated by the driver, the EIPs
+// associated before the EIP was recorded are owned as well
+func (d *Driver) ownsEip(allocationId string) bool {
+	return !d.ExistingEip && (d.EipId == "" || d.EipId == allocationId)
+}
+
+// configureEip associates the existing EIP, or a newly allocated one, with the
+// instance in VPC
+func (d *Driver) configureEip(instanceId string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	allocationId := d.EipId
+	if d.ExistingEip {
+		d.rollback.add("association of EIP "+allocationId, func() error {
+			return d.unassociateEip(allocationId, instanceId)
+		})
+	} else {
+		eipArgs := ecs.AllocateEipAddressArgs{
+			RegionId:           d.Region,
+			Bandwidth:          d.InternetMaxBandwidthOut,
+			InternetChargeType: d.InternetChargeType,
+			ClientToken:        client.GenerateClientToken(),
+		}
+		log.Infof("%s | Allocating Eip address for instance %s ...", d.MachineName, instanceId)
+
+		_, allocationId, err = client.AllocateEipAddress(&eipArgs)
+		if err != nil {
+			return fmt.Errorf("%s | Failed to allocate EIP address: %v", d.MachineName, err)
+		}
+		d.EipId = allocationId
+		d.rollback.add("EIP "+allocationId, func() error {
+			if err := d.releaseEip(allocationId, instanceId); err != nil {
+				return err
+			}
+			d.EipId = ""
+			return nil
+		})
+		err = client.WaitForEip(d.Region, allocationId, ecs.EipStatusAvailable, 60)
+		if err != nil {
+			return fmt.Errorf("%s | Failed to wait EIP %s: %v", d.MachineName, allocationId, err)
+		}
+	}
+
+	log.Infof("%s | Associating Eip address %s for instance %s ...", d.MachineName, allocationId, instanceId)
+	err = client.AssociateEipAddress(allocationId, instanceId)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to associate EIP address: %v", d.MachineName, err)
+	}
+	err = client.WaitForEip(d.Region, allocationId, ecs.EipStatusInUse, 60)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to wait EIP %s: %v", d.MachineName, allocationId, err)
+	}
+	return nil
+}
+
+// removeEip unassociates the EIP from the instance, and releases it if it is
+// owned by the driver
+func (d *Driver) removeEip(allocationId string, instanceId string) {
+	client, err := d.getClient()
+	if err != nil {
+		log.Errorf("%s | Failed to remove EIP %s: %v", d.MachineName, allocationId, err)
+		return
+	}
+
+	err = client.UnassociateEipAddress(allocationId, instanceId)
+	if err != nil {
+		log.Errorf("%s | Failed to unassociate EIP address from instance %s: %v", d.MachineName, instanceId, err)
+	}
+	err = client.WaitForEip(d.Region, allocationId, ecs.EipStatusAvailable, 0)
+	if err != nil {
+		log.Errorf("%s | Failed to wait EIP %s available: %v", d.MachineName, allocationId, err)
+	}
+
+	if !d.ownsEip(allocationId) {
+		log.Infof("%s | Keeping EIP %s which is not allocated by docker-machine", d.MachineName, allocationId)
+		return
+	}
+	err = client.ReleaseEipAddress(allocationId)
+	if err != nil {
+		log.Errorf("%s | Failed to release EIP address: %v", d.MachineName, err)
+	}
+}
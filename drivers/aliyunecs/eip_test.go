@@ -0,0 +1,114 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfigFromFlagsEip(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-eip-address"] = "47.100.1.1"
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.True(t, d.ExistingEip)
+
+	flags.Data["aliyunecs-eip-id"] = "eip-existing"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestCheckEip(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	eips := ecs.DescribeEipAddressesResponse{}
+	f.handle("DescribeEipAddresses", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "47.100.1.1", params.Get("EipAddress"))
+		return http.StatusOK, eips
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.EipAddress = "47.100.1.1"
+	d.ExistingEip = true
+
+	// The EIP is only associated with the instance in VPC
+	assert.Error(t, d.checkEip())
+
+	d.VpcId = "vpc-test"
+	assert.Error(t, d.checkEip())
+
+	eips.EipAddresses.EipAddress = []ecs.EipAddressSetType{{AllocationId: "eip-existing", IpAddress: "47.100.1.1", Status: ecs.EipStatusInUse}}
+	assert.Error(t, d.checkEip())
+
+	eips.EipAddresses.EipAddress[0].Status = ecs.EipStatusAvailable
+	assert.NoError(t, d.checkEip())
+	assert.Equal(t, "eip-existing", d.EipId)
+}
+
+func TestConfigureExistingEip(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	// The EIP is in use once associated until it is unassociated
+	f.handle("DescribeEipAddresses", func(url.Values) (int, interface{}) {
+		status := ecs.EipStatusInUse
+		if f.hasCalled("UnassociateEipAddress") {
+			status = ecs.EipStatusAvailable
+		}
+		eips := ecs.DescribeEipAddressesResponse{}
+		eips.EipAddresses.EipAddress = []ecs.EipAddressSetType{{AllocationId: "eip-existing", Status: status}}
+		return http.StatusOK, eips
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.EipId = "eip-existing"
+	d.ExistingEip = true
+	d.rollback = &rollback{}
+
+	assert.NoError(t, d.configureEip("i-test"))
+	assert.False(t, f.hasCalled("AllocateEipAddress"))
+	assert.True(t, f.hasCalled("AssociateEipAddress"))
+
+	// The existing EIP is unassociated but not released by the rollback
+	assert.Empty(t, d.rollback.run(d.MachineName))
+	assert.True(t, f.hasCalled("UnassociateEipAddress"))
+	assert.False(t, f.hasCalled("ReleaseEipAddress"))
+}
+
+func TestRemoveEip(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	eips := ecs.DescribeEipAddressesResponse{}
+	eips.EipAddresses.EipAddress = []ecs.EipAddressSetType{{AllocationId: "eip-test", Status: ecs.EipStatusAvailable}}
+	f.respond("DescribeEipAddresses", eips)
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.EipId = "eip-test"
+	d.ExistingEip = true
+	d.removeEip("eip-test", "i-test")
+	assert.True(t, f.hasCalled("UnassociateEipAddress"))
+	assert.False(t, f.hasCalled("ReleaseEipAddress"))
+
+	// The EIP associated by others is kept as well
+	d.ExistingEip = false
+	d.removeEip("eip-other", "i-test")
+	assert.False(t, f.hasCalled("ReleaseEipAddress"))
+
+	d.removeEip("eip-test", "i-test")
+	assert.True(t, f.hasCalled("ReleaseEipAddress"))
+}
@@ -0,0 +1,116 @@
+package aliyunecs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+)
+
+// fakeECS is a minimal ECS API endpoint used with --aliyunecs-api-endpoint
+type fakeECS struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	handlers map[string]func(params url.Values) (int, interface{})
+	actions  []string
+}
+
+func newFakeECS() *fakeECS {
+	f := &fakeECS{
+		handlers: map[string]func(params url.Values) (int, interface{}){},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+
+	instanceTypes := ecs.DescribeInstanceTypesResponse{}
+	instanceTypes.InstanceTypes.InstanceType = []ecs.InstanceTypeItemType{
+		{InstanceTypeId: defaultInstanceType, CpuCoreCount: 1, MemorySize: 1},
+	}
+	f.respond("DescribeInstanceTypes", instanceTypes)
+
+	zones := ecs.DescribeZonesResponse{}
+	zones.Zones.Zone = []ecs.ZoneType{
+		{
+			ZoneId: "cn-hangzhou-a",
+			AvailableResourceCreation: ecs.AvailableResourceCreationType{
+				ResourceTypes: []ecs.ResourceType{ecs.ResourceTypeInstance, ecs.ResourceTypeIOOptimizedInstance, ecs.ResourceTypeDisk, ecs.ResourceTypeVSwitch},
+			},
+			AvailableDiskCategories: ecs.AvailableDiskCategoriesType{
+				DiskCategories: []ecs.DiskCategory{ecs.DiskCategoryCloud, ecs.DiskCategoryCloudEfficiency, ecs.DiskCategoryCloudSSD},
+			},
+		},
+	}
+	f.respond("DescribeZones", zones)
+	return f
+}
+
+func (f *fakeECS) handle(action string, handler func(params url.Values) (int, interface{})) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.handlers[action] = handler
+}
+
+func (f *fakeECS) respond(action string, response interface{}) {
+	f.handle(action, func(url.Values) (int, interface{}) {
+		return http.StatusOK, response
+	})
+}
+
+func (f *fakeECS) fail(action string, code string) {
+	f.handle(action, func(url.Values) (int, interface{}) {
+		return http.StatusBadRequest, common.ErrorResponse{
+			Code:    code,
+			Message: "fake failure of " + action,
+		}
+	})
+}
+
+// called returns the actions invoked in order
+func (f *fakeECS) called() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.actions...)
+}
+
+func (f *fakeECS) hasCalled(action string) bool {
+	for _, a := range f.called() {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeECS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	action := params.Get("Action")
+
+	f.lock.Lock()
+	f.actions = append(f.actions, action)
+	handler, ok := f.handlers[action]
+	f.lock.Unlock()
+
+	statusCode := http.StatusOK
+	var response interface{} = common.Response{RequestId: "fake"}
+	if ok {
+		statusCode, response = handler(params)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+func getFakeECSDriver(f *fakeECS) (*Driver, error) {
+	d, err := getTestDriver()
+	if err != nil {
+		return nil, err
+	}
+	d.APIEndpoint = f.URL
+	d.SLBAPIEndpoint = f.URL
+	return d, nil
+}
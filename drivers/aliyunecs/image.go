@@ -0,0 +1,263 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/state"
+)
+
+var (
+	// The interval to poll the status of image being created
+	imageWaitInterval = 5 * time.Second
+	// Creating the image takes much longer than starting the instance, it
+	// is in proportion to the data on the system disk
+	imageTimeout = 60 * time.Minute
+)
+
+// The tag of the image created from a provisioned machine, the machines
+// created from it skip the installation of Docker
+const (
+	engineImageTagKey   = "docker-machine-engine"
+	engineImageTagValue = "installed"
+)
+
+// imageMatcher matches the image name or id with a glob pattern, or with a
+// regular expression enclosed in slashes, e.g. /^ubuntu_16/
+type imageMatcher func(name string) bool
+
+func newImageMatcher(pattern string) (imageMatcher, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}, nil
+}
+
+func validateImageOwner(owner string) (ecs.ImageOwnerAlias, error) {
+	switch alias := ecs.ImageOwnerAlias(owner); alias {
+	case ecs.ImageOwnerSystem, ecs.ImageOwnerSelf, ecs.ImageOwnerOthers, ecs.ImageOwnerMarketplace:
+		return alias, nil
+	case ecs.ImageOwnerDefault:
+		return ecs.ImageOwnerSystem, nil
+	}
+	return "", fmt.Errorf("the value should be system, self, others or marketplace")
+}
+
+// findNewestImage returns the newest available image whose name or id is
+// matched, nil if nothing matched
+func (d *Driver) findNewestImage(match imageMatcher, owner ecs.ImageOwnerAlias) (*ecs.ImageType, error) {
+	args := ecs.DescribeImagesArgs{
+		RegionId:        d.Region,
+		ImageOwnerAlias: owner,
+	}
+
+	var newest *ecs.ImageType
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		images, pagination, err := client.DescribeImages(&args)
+		if err != nil {
+			return nil, err
+		}
+		for i, image := range images {
+			if image.Status != "" && image.Status != ecs.ImageStatusAvailable {
+				continue
+			}
+			if !match(image.ImageName) && !match(image.ImageId) {
+				continue
+			}
+			if newest == nil || time.Time(image.CreationTime).After(time.Time(newest.CreationTime)) {
+				newest = &images[i]
+			}
+		}
+		nextPage := pagination.NextPage()
+		if nextPage == nil {
+			break
+		}
+		args.Pagination = *nextPage
+	}
+	return newest, nil
+}
+
+// resolveImageID returns the image to create instance with, which is the
+// --aliyunecs-image-id if specified, or the newest image matched with
+// --aliyunecs-image-name, or the latest Ubuntu 14.04 provided by system
+func (d *Driver) resolveImageID() (string, error) {
+	if d.ImageID != "" {
+		return d.ImageID, nil
+	}
+
+	owner, err := validateImageOwner(d.ImageOwner)
+	if err != nil {
+		return "", err
+	}
+
+	if d.ImageName != "" {
+		match, err := newImageMatcher(d.ImageName)
+		if err != nil {
+			return "", err
+		}
+		image, err := d.findNewestImage(match, owner)
+		if err != nil {
+			return "", fmt.Errorf("%s | Failed to describe images: %v", d.MachineName, err)
+		}
+		if image == nil {
+			return "", fmt.Errorf("%s | No %s image matched with %q in region %s", d.MachineName, owner, d.ImageName, d.Region)
+		}
+		log.Infof("%s | Using image %s (%s) matched with %q", d.MachineName, image.ImageId, image.ImageName, d.ImageName)
+		return image.ImageId, nil
+	}
+
+	// Scan registered images with prefix of ubuntu1404_64_20G_
+	match, _ := newImageMatcher(defaultUbuntuImagePrefix + "*")
+	image, err := d.findNewestImage(match, ecs.ImageOwnerSystem)
+	if err != nil {
+		log.Errorf("%s | Failed to describe images: %v", d.MachineName, err)
+	}
+	if image != nil {
+		return image.ImageId, nil
+	}
+
+	//Default use the config Ubuntu 14.04 64bits image
+	return defaultUbuntuImageID, nil
+}
+
+func (d *Driver) getImage(imageId string) (*ecs.ImageType, error) {
+	args := ecs.DescribeImagesArgs{
+		RegionId: d.Region,
+		ImageId:  imageId,
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	images, _, err := client.DescribeImages(&args)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("image %s not found in region %s", imageId, d.Region)
+	}
+	return &images[0], nil
+}
+
+// waitForImage waits for the image to be available
+func (d *Driver) waitForImage(imageId string) error {
+	deadline := time.Now().Add(imageTimeout)
+	for {
+		image, err := d.getImage(imageId)
+		if err != nil {
+			return err
+		}
+		switch image.Status {
+		case ecs.ImageStatusAvailable:
+			return nil
+		case ecs.ImageStatusCreateFailed, ecs.ImageStatusUnAvailable:
+			return fmt.Errorf("image %s is %s", imageId, image.Status)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for image %s, progress %s", imageId, image.Progress)
+		}
+		time.Sleep(imageWaitInterval)
+	}
+}
+
+// CreateImage creates a custom image from the system disk of instance, the
+// instance is stopped for a consistent disk and started again if it was
+// running
+func (d *Driver) CreateImage(name string) (string, error) {
+	if d.InstanceId == "" {
+		return "", fmt.Errorf("%s | Unknown instance id", d.MachineName)
+	}
+
+	s, err := d.GetState()
+	if err != nil {
+		return "", err
+	}
+	running := s == state.Running
+	if running {
+		log.Infof("%s | Stopping instance %s to create image ...", d.MachineName, d.InstanceId)
+		if err := d.Stop(); err != nil {
+			return "", err
+		}
+	}
+
+	log.Infof("%s | Creating image %s of instance %s ...", d.MachineName, name, d.InstanceId)
+	client, err := d.getClient()
+	if err != nil {
+		return "", err
+	}
+	args := createImageArgs{
+		RegionId:    d.Region,
+		InstanceId:  d.InstanceId,
+		ImageName:   name,
+		Description: fmt.Sprintf("Image of %s created by Docker Machine", d.MachineName),
+		ClientToken: client.GenerateClientToken(),
+	}
+	imageId, err := d.createImage(&args)
+	if err == nil {
+		if err = d.waitForImage(imageId); err != nil {
+			err = fmt.Errorf("%s | Failed to wait image %s available: %v", d.MachineName, imageId, err)
+		} else if tagErr := d.addResourceTags(ecs.TagResourceImage, imageId, map[string]string{engineImageTagKey: engineImageTagValue}); tagErr != nil {
+			log.Warnf("%s | Failed to tag image %s, Docker is installed again on the machines created from it: %v", d.MachineName, imageId, tagErr)
+		}
+	} else {
+		err = fmt.Errorf("%s | Failed to create image of instance %s: %v", d.MachineName, d.InstanceId, err)
+	}
+
+	if running {
+		log.Infof("%s | Starting instance %s ...", d.MachineName, d.InstanceId)
+		if startErr := d.Start(); startErr != nil && err == nil {
+			err = startErr
+		}
+	}
+	if err != nil {
+		return imageId, err
+	}
+
+	log.Infof("%s | Image %s is available", d.MachineName, imageId)
+	return imageId, nil
+}
+
+// isEngineImage returns true if the image is created from a provisioned
+// machine with Docker installed
+func (d *Driver) isEngineImage(imageId string) (bool, error) {
+	args := ecs.DescribeTagsArgs{
+		RegionId:     d.Region,
+		ResourceType: ecs.TagResourceImage,
+		ResourceId:   imageId,
+		Tag:          map[string]string{engineImageTagKey: engineImageTagValue},
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return false, err
+	}
+	tags, _, err := client.DescribeTags(&args)
+	if err != nil {
+		return false, err
+	}
+	return len(tags) > 0, nil
+}
+
+// EnginePreinstalled returns true if the instance is created from the image
+// of a provisioned machine, so the provisioner uses the Docker installed
+func (d *Driver) EnginePreinstalled() bool {
+	return d.ImageEngineInstalled
+}
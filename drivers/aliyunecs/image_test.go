@@ -0,0 +1,191 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/denverdino/aliyungo/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewImageMatcher(t *testing.T) {
+	match, err := newImageMatcher("ubuntu_16*")
+	assert.NoError(t, err)
+	assert.True(t, match("ubuntu_16_0402_64_40G_base_20170222.vhd"))
+	assert.False(t, match("centos_7_2_64_40G_base_20170222.vhd"))
+
+	match, err = newImageMatcher("/^(ubuntu|debian)_/")
+	assert.NoError(t, err)
+	assert.True(t, match("debian_8_06_64_40G_alibase_20170222.vhd"))
+	assert.False(t, match("centos_7_2_64_40G_base_20170222.vhd"))
+
+	_, err = newImageMatcher("/(/")
+	assert.Error(t, err)
+	_, err = newImageMatcher("[")
+	assert.Error(t, err)
+}
+
+func newImage(id string, created time.Time, status ecs.ImageStatus) ecs.ImageType {
+	return ecs.ImageType{
+		ImageId:      id,
+		ImageName:    id,
+		Status:       status,
+		CreationTime: util.NewISO6801Time(created),
+	}
+}
+
+func TestResolveImageID(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	now := time.Now()
+	f.handle("DescribeImages", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "self", params.Get("ImageOwnerAlias"))
+		images := ecs.DescribeImagesResponse{}
+		images.TotalCount = 4
+		images.PageSize = 2
+		switch params.Get("PageNumber") {
+		case "", "1":
+			images.PageNumber = 1
+			images.Images.Image = []ecs.ImageType{
+				newImage("m-old", now.Add(-48*time.Hour), ecs.ImageStatusAvailable),
+				newImage("m-other", now, ecs.ImageStatusAvailable),
+			}
+		default:
+			images.PageNumber = 2
+			images.Images.Image = []ecs.ImageType{
+				newImage("m-new", now.Add(-time.Hour), ecs.ImageStatusAvailable),
+				newImage("m-creating", now, ecs.ImageStatusCreating),
+			}
+		}
+		return http.StatusOK, images
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.ImageID = ""
+	d.ImageOwner = "self"
+
+	d.ImageName = "/^m-(old|new|creating)$/"
+	imageID, err := d.resolveImageID()
+	assert.NoError(t, err)
+	assert.Equal(t, "m-new", imageID)
+
+	d.ImageName = "missing-*"
+	_, err = d.resolveImageID()
+	assert.Error(t, err)
+}
+
+func TestSetConfigFromFlagsImage(t *testing.T) {
+	d, err := getTestDriver()
+	assert.NoError(t, err)
+	defer cleanup()
+
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-image-name"] = "ubuntu_16*"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+
+	delete(flags.Data, "aliyunecs-image-id")
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.Equal(t, "ubuntu_16*", d.ImageName)
+
+	flags.Data["aliyunecs-image-owner"] = "anyone"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestCreateImage(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	defer func(interval time.Duration) { imageWaitInterval = interval }(imageWaitInterval)
+	imageWaitInterval = time.Millisecond
+
+	status := ecs.Running
+	f.handle("DescribeInstanceAttribute", func(params url.Values) (int, interface{}) {
+		return http.StatusOK, ecs.InstanceAttributesType{InstanceId: "i-test", Status: status}
+	})
+	f.handle("StopInstance", func(params url.Values) (int, interface{}) {
+		status = ecs.Stopped
+		return http.StatusOK, struct{}{}
+	})
+	f.handle("StartInstance", func(params url.Values) (int, interface{}) {
+		status = ecs.Running
+		return http.StatusOK, struct{}{}
+	})
+	f.handle("CreateImage", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, ecs.Stopped, status)
+		assert.Equal(t, "i-test", params.Get("InstanceId"))
+		assert.Equal(t, "docker-base", params.Get("ImageName"))
+		return http.StatusOK, ecs.CreateImageResponse{ImageId: "m-test"}
+	})
+	polls := 0
+	f.handle("DescribeImages", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "m-test", params.Get("ImageId"))
+		polls++
+		images := ecs.DescribeImagesResponse{}
+		image := newImage("m-test", time.Now(), ecs.ImageStatusCreating)
+		if polls > 1 {
+			image.Status = ecs.ImageStatusAvailable
+		}
+		images.Images.Image = []ecs.ImageType{image}
+		return http.StatusOK, images
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+
+	imageId, err := d.CreateImage("docker-base")
+	assert.NoError(t, err)
+	assert.Equal(t, "m-test", imageId)
+	assert.Equal(t, 2, polls)
+	assert.True(t, f.hasCalled("StopInstance"))
+	assert.True(t, f.hasCalled("StartInstance"))
+	assert.True(t, f.hasCalled("AddTags"))
+}
+
+func TestIsEngineImage(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.handle("DescribeTags", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "image", params.Get("ResourceType"))
+		response := ecs.DescribeTagsResponse{}
+		if params.Get("ResourceId") == "m-engine" {
+			response.Tags.Tag = []ecs.TagItemType{{TagKey: engineImageTagKey, TagValue: engineImageTagValue}}
+		}
+		return http.StatusOK, response
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+
+	installed, err := d.isEngineImage("m-engine")
+	assert.NoError(t, err)
+	assert.True(t, installed)
+
+	installed, err = d.isEngineImage("m-other")
+	assert.NoError(t, err)
+	assert.False(t, installed)
+	assert.False(t, d.EnginePreinstalled())
+}
+
+func TestCreateImageStoppedInstance(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.respond("DescribeInstanceAttribute", ecs.InstanceAttributesType{InstanceId: "i-test", Status: ecs.Stopped})
+	f.fail("CreateImage", "QuotaExceed.Image")
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+
+	_, err = d.CreateImage("docker-base")
+	assert.Error(t, err)
+	assert.False(t, f.hasCalled("StopInstance"))
+	assert.False(t, f.hasCalled("StartInstance"))
+}
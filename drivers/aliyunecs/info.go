@@ -0,0 +1,90 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+// GetDriverInfo lists the regions, the zones of --aliyunecs-region with the
+// instance types and disk categories available in them
+func (d *Driver) GetDriverInfo() ([]drivers.InfoTable, error) {
+	regions, err := d.getRegions()
+	if err != nil {
+		return nil, err
+	}
+	zones, err := d.getZones()
+	if err != nil {
+		return nil, err
+	}
+	instanceTypes, err := d.getInstanceTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	regionTable := drivers.InfoTable{
+		Title:  "Regions",
+		Header: []string{"REGION", "NAME"},
+	}
+	for _, region := range regions {
+		regionTable.Rows = append(regionTable.Rows, []string{string(region.RegionId), region.LocalName})
+	}
+
+	zoneTable := drivers.InfoTable{
+		Title:  fmt.Sprintf("Zones in %s", d.Region),
+		Header: []string{"ZONE", "NAME", "RESOURCES"},
+	}
+	categoryZones := map[ecs.DiskCategory][]string{}
+	for _, zone := range zones {
+		resources := []string{}
+		for _, t := range zone.AvailableResourceCreation.ResourceTypes {
+			resources = append(resources, string(t))
+		}
+		zoneTable.Rows = append(zoneTable.Rows, []string{zone.ZoneId, zone.LocalName, strings.Join(resources, ", ")})
+
+		for _, c := range zone.AvailableDiskCategories.DiskCategories {
+			categoryZones[c] = append(categoryZones[c], zone.ZoneId)
+		}
+	}
+
+	sort.Sort(instanceTypesBySize(instanceTypes))
+	instanceTypeTable := drivers.InfoTable{
+		Title:  fmt.Sprintf("Instance types in %s", d.Region),
+		Header: []string{"INSTANCE TYPE", "CPU", "MEMORY", "ZONES"},
+	}
+	for _, t := range instanceTypes {
+		available := []string{}
+		for _, zone := range zones {
+			if isInstanceTypeAvailable(t.InstanceTypeId, []zoneAttributes{zone}) {
+				available = append(available, zone.ZoneId)
+			}
+		}
+		if len(available) == 0 {
+			continue
+		}
+		instanceTypeTable.Rows = append(instanceTypeTable.Rows, []string{
+			t.InstanceTypeId,
+			fmt.Sprintf("%d", t.CpuCoreCount),
+			fmt.Sprintf("%g GB", t.MemorySize),
+			strings.Join(available, ", "),
+		})
+	}
+
+	categories := []string{}
+	for c := range categoryZones {
+		categories = append(categories, string(c))
+	}
+	sort.Strings(categories)
+	categoryTable := drivers.InfoTable{
+		Title:  fmt.Sprintf("Disk categories in %s", d.Region),
+		Header: []string{"DISK CATEGORY", "ZONES"},
+	}
+	for _, c := range categories {
+		categoryTable.Rows = append(categoryTable.Rows, []string{c, strings.Join(categoryZones[ecs.DiskCategory(c)], ", ")})
+	}
+
+	return []drivers.InfoTable{regionTable, zoneTable, instanceTypeTable, categoryTable}, nil
+}
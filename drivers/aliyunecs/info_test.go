@@ -0,0 +1,43 @@
+package aliyunecs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDriverInfo(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeRegions(f)
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.StorePath, err = ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d.StorePath)
+
+	tables, err := d.GetDriverInfo()
+	assert.NoError(t, err)
+	if !assert.Len(t, tables, 4) {
+		return
+	}
+	assert.Equal(t, []string{"cn-hangzhou", "China East 1"}, tables[0].Rows[0])
+	assert.Equal(t, "Zones in cn-hangzhou", tables[1].Title)
+	assert.Equal(t, []string{"cn-hangzhou-a", "", "Instance, IoOptimized, Disk, VSwitch"}, tables[1].Rows[0])
+	assert.Equal(t, [][]string{{defaultInstanceType, "1", "1 GB", "cn-hangzhou-a"}}, tables[2].Rows)
+	assert.Equal(t, []string{"cloud", "cn-hangzhou-a"}, tables[3].Rows[0])
+	assert.Len(t, tables[3].Rows, 3)
+
+	// The catalogues are cached
+	_, err = os.Stat(d.cachePath(d.zonesCacheFile()))
+	assert.NoError(t, err)
+	_, err = os.Stat(d.cachePath(instanceTypesCacheFile))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,240 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// Maximum number of the DescribePrice calls in flight, the API prices one
+// instance type per call
+const maxPriceQueries = 10
+
+func (d *Driver) ioOptimized() ecs.IoOptimized {
+	if d.IoOptimized {
+		return ecs.IoOptimizedOptimized
+	}
+	return ecs.IoOptimizedNone
+}
+
+// checkInstanceType validates the zone, instance type and disk categories
+// before any resource is created, and selects the instance type in auto mode
+func (d *Driver) checkInstanceType() error {
+	zones, err := d.describeZones()
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe zones in region %s: %v", d.MachineName, d.Region, err)
+	}
+
+	// The zones to launch instance in, it is decided by ECS if not specified
+	candidates := zones
+	if d.Zone != "" {
+		candidates = nil
+		names := []string{}
+		for _, zone := range zones {
+			names = append(names, zone.ZoneId)
+			if zone.ZoneId == d.Zone {
+				candidates = append(candidates, zone)
+			}
+		}
+		if len(candidates) == 0 {
+			return fmt.Errorf("%s | Invalid --aliyunecs-zone %s: The value should be one of %s", d.MachineName, d.Zone, strings.Join(names, ", "))
+		}
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	instanceTypes, err := client.DescribeInstanceTypes()
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe instance types: %v", d.MachineName, err)
+	}
+
+	if d.InstanceTypeAuto {
+		instanceType, err := d.selectInstanceType(instanceTypes, candidates)
+		if err != nil {
+			return err
+		}
+		d.InstanceType = instanceType
+	} else {
+		found := false
+		for _, t := range instanceTypes {
+			if t.InstanceTypeId == d.InstanceType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s | Invalid --aliyunecs-instance-type %s: No such instance type", d.MachineName, d.InstanceType)
+		}
+		if !isInstanceTypeAvailable(d.InstanceType, candidates) {
+			return fmt.Errorf("%s | Instance type %s is not available in %s", d.MachineName, d.InstanceType, d.zoneDescription())
+		}
+	}
+
+	if d.IoOptimized {
+		if !isResourceAvailable(ecs.ResourceTypeIOOptimizedInstance, candidates) {
+			return fmt.Errorf("%s | I/O optimized instance is not available in %s", d.MachineName, d.zoneDescription())
+		}
+		for _, category := range d.diskCategories() {
+			if category == ecs.DiskCategoryCloud || category == ecs.DiskCategoryEphemeral {
+				return fmt.Errorf("%s | The disk category %s is not supported by I/O optimized instance", d.MachineName, category)
+			}
+		}
+	}
+
+	if d.SystemDiskCategory != "" && !isDiskCategoryAvailable(d.SystemDiskCategory, candidates) {
+		return fmt.Errorf("%s | Invalid --aliyunecs-system-disk-category %s: It is not available in %s", d.MachineName, d.SystemDiskCategory, d.zoneDescription())
+	}
+	for _, disk := range d.DataDisks {
+		if disk.Category != "" && !isDiskCategoryAvailable(disk.Category, candidates) {
+			return fmt.Errorf("%s | Invalid category %s of data disk on %s: It is not available in %s", d.MachineName, disk.Category, disk.MountPoint, d.zoneDescription())
+		}
+	}
+	return nil
+}
+
+func (d *Driver) diskCategories() []ecs.DiskCategory {
+	categories := []ecs.DiskCategory{d.SystemDiskCategory}
+	for _, disk := range d.DataDisks {
+		categories = append(categories, disk.Category)
+	}
+	return categories
+}
+
+func (d *Driver) zoneDescription() string {
+	if d.Zone != "" {
+		return "zone " + d.Zone
+	}
+	return "region " + string(d.Region)
+}
+
+// isInstanceTypeAvailable returns true if any of zones supports the instance
+// type, the zones without the list of instance types support all of them
+func isInstanceTypeAvailable(instanceType string, zones []zoneAttributes) bool {
+	for _, zone := range zones {
+		if len(zone.AvailableInstanceTypes.InstanceTypes) == 0 {
+			return true
+		}
+		for _, t := range zone.AvailableInstanceTypes.InstanceTypes {
+			if t == instanceType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isResourceAvailable(resourceType ecs.ResourceType, zones []zoneAttributes) bool {
+	for _, zone := range zones {
+		for _, t := range zone.AvailableResourceCreation.ResourceTypes {
+			if t == resourceType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isDiskCategoryAvailable(category ecs.DiskCategory, zones []zoneAttributes) bool {
+	for _, zone := range zones {
+		for _, c := range zone.AvailableDiskCategories.DiskCategories {
+			if c == category {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type instanceTypesBySize []ecs.InstanceTypeItemType
+
+func (s instanceTypesBySize) Len() int      { return len(s) }
+func (s instanceTypesBySize) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s instanceTypesBySize) Less(i, j int) bool {
+	if s[i].CpuCoreCount != s[j].CpuCoreCount {
+		return s[i].CpuCoreCount < s[j].CpuCoreCount
+	}
+	if s[i].MemorySize != s[j].MemorySize {
+		return s[i].MemorySize < s[j].MemorySize
+	}
+	return s[i].InstanceTypeId < s[j].InstanceTypeId
+}
+
+// selectInstanceType returns the cheapest instance type available in zones
+// with the minimum CPU and memory, the smallest one is chosen if the price is
+// unknown
+func (d *Driver) selectInstanceType(instanceTypes []ecs.InstanceTypeItemType, zones []zoneAttributes) (string, error) {
+	matched := []ecs.InstanceTypeItemType{}
+	for _, t := range instanceTypes {
+		if t.CpuCoreCount >= d.MinCPU && t.MemorySize >= float64(d.MinMemory) && isInstanceTypeAvailable(t.InstanceTypeId, zones) {
+			matched = append(matched, t)
+		}
+	}
+	if len(matched) == 0 {
+		return "", fmt.Errorf("%s | No instance type with at least %d CPU and %d GB memory is available in %s", d.MachineName, d.MinCPU, d.MinMemory, d.zoneDescription())
+	}
+	sort.Sort(instanceTypesBySize(matched))
+
+	prices, err := d.describeInstancePrices(matched)
+	if err != nil {
+		return "", err
+	}
+
+	selected := matched[0].InstanceTypeId
+	lowest := -1.0
+	for _, t := range matched {
+		price, ok := prices[t.InstanceTypeId]
+		if !ok {
+			continue
+		}
+		if lowest < 0 || price < lowest {
+			selected = t.InstanceTypeId
+			lowest = price
+		}
+	}
+
+	log.Infof("%s | Selected instance type %s", d.MachineName, selected)
+	return selected, nil
+}
+
+// describeInstancePrices returns the hourly prices of the instance types, at
+// most maxPriceQueries of them are priced at a time. The instance types failed
+// to price are left out.
+func (d *Driver) describeInstancePrices(instanceTypes []ecs.InstanceTypeItemType) (map[string]float64, error) {
+	// The client is shared by the queries
+	if _, err := d.getClient(); err != nil {
+		return nil, err
+	}
+
+	var (
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+		queries = make(chan struct{}, maxPriceQueries)
+		prices  = map[string]float64{}
+	)
+	for _, t := range instanceTypes {
+		wg.Add(1)
+		go func(instanceType string) {
+			defer wg.Done()
+			queries <- struct{}{}
+			defer func() { <-queries }()
+
+			price, err := d.describeInstancePrice(instanceType, d.ioOptimized())
+			if err != nil {
+				log.Debugf("%s | Failed to describe price of instance type %s: %v", d.MachineName, instanceType, err)
+				return
+			}
+			lock.Lock()
+			prices[instanceType] = price
+			lock.Unlock()
+		}(t.InstanceTypeId)
+	}
+	wg.Wait()
+
+	return prices, nil
+}
@@ -0,0 +1,157 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeInstanceTypeECS() *fakeECS {
+	f := newFakeECS()
+
+	instanceTypes := ecs.DescribeInstanceTypesResponse{}
+	instanceTypes.InstanceTypes.InstanceType = []ecs.InstanceTypeItemType{
+		{InstanceTypeId: "ecs.n1.tiny", CpuCoreCount: 1, MemorySize: 1},
+		{InstanceTypeId: "ecs.n1.small", CpuCoreCount: 1, MemorySize: 2},
+		{InstanceTypeId: "ecs.s2.large", CpuCoreCount: 2, MemorySize: 4},
+		{InstanceTypeId: "ecs.n1.medium", CpuCoreCount: 2, MemorySize: 4},
+		{InstanceTypeId: "ecs.n1.large", CpuCoreCount: 4, MemorySize: 8},
+	}
+	f.respond("DescribeInstanceTypes", instanceTypes)
+
+	zones := describeZonesResponse{}
+	zoneA := zoneAttributes{}
+	zoneA.ZoneId = "cn-hangzhou-a"
+	zoneA.AvailableResourceCreation.ResourceTypes = []ecs.ResourceType{ecs.ResourceTypeInstance}
+	zoneA.AvailableDiskCategories.DiskCategories = []ecs.DiskCategory{ecs.DiskCategoryCloud}
+	zoneA.AvailableInstanceTypes.InstanceTypes = []string{"ecs.n1.tiny", "ecs.n1.small"}
+	zoneB := zoneAttributes{}
+	zoneB.ZoneId = "cn-hangzhou-b"
+	zoneB.AvailableResourceCreation.ResourceTypes = []ecs.ResourceType{ecs.ResourceTypeInstance, ecs.ResourceTypeIOOptimizedInstance}
+	zoneB.AvailableDiskCategories.DiskCategories = []ecs.DiskCategory{ecs.DiskCategoryCloudEfficiency, ecs.DiskCategoryCloudSSD}
+	zoneB.AvailableInstanceTypes.InstanceTypes = []string{"ecs.n1.small", "ecs.s2.large", "ecs.n1.medium", "ecs.n1.large"}
+	zones.Zones.Zone = []zoneAttributes{zoneA, zoneB}
+	f.respond("DescribeZones", zones)
+
+	prices := map[string]float64{
+		"ecs.n1.small":  0.5,
+		"ecs.s2.large":  1.2,
+		"ecs.n1.medium": 0.9,
+		"ecs.n1.large":  1.8,
+	}
+	f.handle("DescribePrice", func(params url.Values) (int, interface{}) {
+		price, ok := prices[params.Get("InstanceType")]
+		if !ok {
+			return http.StatusBadRequest, common.ErrorResponse{Code: "InvalidInstanceType.ValueNotSupported"}
+		}
+		response := describePriceResponse{}
+		response.PriceInfo.Price.TradePrice = price
+		return http.StatusOK, response
+	})
+	return f
+}
+
+func TestCheckInstanceType(t *testing.T) {
+	f := newFakeInstanceTypeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+
+	d.InstanceType = "ecs.n1.tiny"
+	assert.NoError(t, d.checkInstanceType())
+
+	d.InstanceType = "ecs.n1.tyni"
+	assert.Error(t, d.checkInstanceType())
+
+	d.InstanceType = "ecs.n1.large"
+	d.Zone = "cn-hangzhou-a"
+	assert.Error(t, d.checkInstanceType())
+
+	d.Zone = "cn-hangzhou-c"
+	assert.Error(t, d.checkInstanceType())
+
+	d.InstanceType = "ecs.n1.small"
+	d.Zone = "cn-hangzhou-a"
+	d.IoOptimized = true
+	assert.Error(t, d.checkInstanceType())
+
+	d.Zone = "cn-hangzhou-b"
+	d.SystemDiskCategory = ecs.DiskCategoryCloudSSD
+	assert.NoError(t, d.checkInstanceType())
+
+	d.SystemDiskCategory = ecs.DiskCategoryCloud
+	assert.Error(t, d.checkInstanceType())
+
+	d.IoOptimized = false
+	assert.Error(t, d.checkInstanceType())
+}
+
+func TestCheckInstanceTypeAuto(t *testing.T) {
+	f := newFakeInstanceTypeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceTypeAuto = true
+	d.MinCPU = 2
+	d.MinMemory = 4
+
+	assert.NoError(t, d.checkInstanceType())
+	assert.Equal(t, "ecs.n1.medium", d.InstanceType)
+
+	d.MinCPU = 1
+	d.MinMemory = 2
+	d.Zone = "cn-hangzhou-a"
+	assert.NoError(t, d.checkInstanceType())
+	assert.Equal(t, "ecs.n1.small", d.InstanceType)
+
+	d.MinCPU = 4
+	assert.Error(t, d.checkInstanceType())
+}
+
+func TestSelectInstanceTypePricesAll(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	// The cheapest one is the largest, e.g. of an older generation
+	instanceTypes := []ecs.InstanceTypeItemType{}
+	for i := 1; i <= 3*maxPriceQueries; i++ {
+		instanceTypes = append(instanceTypes, ecs.InstanceTypeItemType{
+			InstanceTypeId: fmt.Sprintf("ecs.t%d", i),
+			CpuCoreCount:   i,
+			MemorySize:     float64(2 * i),
+		})
+	}
+	f.handle("DescribePrice", func(params url.Values) (int, interface{}) {
+		response := describePriceResponse{}
+		response.PriceInfo.Price.TradePrice = 1.0
+		if params.Get("InstanceType") == fmt.Sprintf("ecs.t%d", 3*maxPriceQueries) {
+			response.PriceInfo.Price.TradePrice = 0.1
+		}
+		return http.StatusOK, response
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.MinCPU = 1
+	d.MinMemory = 1
+
+	// The zone without the list of instance types supports all of them
+	selected, err := d.selectInstanceType(instanceTypes, []zoneAttributes{{}})
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("ecs.t%d", 3*maxPriceQueries), selected)
+
+	priced := 0
+	for _, action := range f.called() {
+		if action == "DescribePrice" {
+			priced++
+		}
+	}
+	assert.Equal(t, len(instanceTypes), priced)
+}
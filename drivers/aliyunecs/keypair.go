@@ -0,0 +1,133 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
+)
+
+// machineKeyPairName returns the name of the key pair imported for machine,
+// which is unique with the driver ID
+func (d *Driver) machineKeyPairName() string {
+	name := strings.Replace(d.MachineName, ".", "-", -1)
+	return fmt.Sprintf("docker-machine-%s-%s", name, d.Id[:8])
+}
+
+// checkKeyPair checks the existing key pair and its private key before any
+// resource is created
+func (d *Driver) checkKeyPair() error {
+	if !d.ExistingKeyPair {
+		return nil
+	}
+
+	if _, err := os.Stat(d.SSHPrivateKeyPath); err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-ssh-keypath: %v", d.MachineName, err)
+	}
+
+	keyPair, err := d.describeKeyPair(d.KeyPairName)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe key pair %s: %v", d.MachineName, d.KeyPairName, err)
+	}
+	if keyPair == nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-keypair-name %s: No such key pair in region %s", d.MachineName, d.KeyPairName, d.Region)
+	}
+	return nil
+}
+
+// createKeyPair generates the SSH key of machine, or copies the private key of
+// the existing key pair
+func (d *Driver) createKeyPair() error {
+
+	log.Debugf("%s | SSH key path: %s", d.MachineName, d.GetSSHKeyPath())
+
+	if d.ExistingKeyPair {
+		log.Debugf("%s | Using the private key %s of key pair %s", d.MachineName, d.SSHPrivateKeyPath, d.KeyPairName)
+		if err := mcnutils.CopyFile(d.SSHPrivateKeyPath, d.GetSSHKeyPath()); err != nil {
+			return err
+		}
+		// The public key is optional, it is only uploaded to adopted instance
+		if err := mcnutils.CopyFile(d.SSHPrivateKeyPath+".pub", d.GetSSHKeyPath()+".pub"); err != nil {
+			log.Debugf("%s | No public key of %s: %v", d.MachineName, d.SSHPrivateKeyPath, err)
+			return nil
+		}
+	} else if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+		return err
+	}
+
+	publicKey, err := ioutil.ReadFile(d.GetSSHKeyPath() + ".pub")
+	if err != nil {
+		return err
+	}
+
+	d.PublicKey = publicKey
+	return nil
+}
+
+// importMachineKeyPair imports the public key of machine as the key pair to
+// launch instance with
+func (d *Driver) importMachineKeyPair() error {
+	if d.ExistingKeyPair {
+		return nil
+	}
+
+	name := d.machineKeyPairName()
+	log.Infof("%s | Importing key pair %s ...", d.MachineName, name)
+	if _, err := d.importKeyPair(name, d.PublicKey); err != nil {
+		return fmt.Errorf("%s | Failed to import key pair %s: %v", d.MachineName, name, err)
+	}
+	d.KeyPairName = name
+	d.rollback.add("key pair "+name, func() error {
+		if err := d.deleteKeyPairs(name); err != nil {
+			return err
+		}
+		d.KeyPairName = ""
+		return nil
+	})
+	return nil
+}
+
+// removeKeyPair deletes the key pair imported for machine, the existing key
+// pair is kept
+func (d *Driver) removeKeyPair() {
+	if d.ExistingKeyPair || d.KeyPairName == "" {
+		return
+	}
+
+	log.Infof("%s | Deleting key pair %s ...", d.MachineName, d.KeyPairName)
+	if err := d.deleteKeyPairs(d.KeyPairName); err != nil {
+		log.Warnf("%s | Failed to delete key pair %s: %v", d.MachineName, d.KeyPairName, err)
+		return
+	}
+	d.KeyPairName = ""
+}
+
+// setupInstance connects to the instance and prepares the host, the public key
+// is uploaded with the password unless the instance has the key pair
+func (d *Driver) setupInstance() error {
+	var (
+		sshClient ssh.Client
+		err       error
+	)
+
+	if d.KeyPairName != "" {
+		log.Infof("%s | Waiting SSH service of %s is ready to connect ...", d.MachineName, d.IPAddress)
+		if err := drivers.WaitForSSH(d); err != nil {
+			return err
+		}
+		sshClient, err = drivers.GetSSHClientFromDriver(d)
+	} else {
+		sshClient, err = d.uploadKeyPair()
+	}
+	if err != nil {
+		return err
+	}
+
+	d.prepareHost(sshClient)
+	return nil
+}
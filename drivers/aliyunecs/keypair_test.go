@@ -0,0 +1,117 @@
+package aliyunecs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfigFromFlagsKeyPair(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-keypair-name"] = "ops"
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	assert.Error(t, d.SetConfigFromFlags(flags))
+
+	flags.Data["aliyunecs-ssh-keypath"] = "/home/ops/.ssh/id_rsa"
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.True(t, d.ExistingKeyPair)
+	assert.Equal(t, "ops", d.KeyPairName)
+}
+
+func TestCreateWithImportedKeyPair(t *testing.T) {
+	f := newFakeCreateECS()
+	defer f.Close()
+
+	var imported, created, deleted url.Values
+	f.handle("ImportKeyPair", func(params url.Values) (int, interface{}) {
+		imported = params
+		return http.StatusOK, struct{}{}
+	})
+	f.handle("CreateInstance", func(params url.Values) (int, interface{}) {
+		created = params
+		return http.StatusBadRequest, struct{ Code string }{"InvalidInstanceType.ValueNotSupported"}
+	})
+	f.handle("DeleteKeyPairs", func(params url.Values) (int, interface{}) {
+		deleted = params
+		return http.StatusOK, struct{}{}
+	})
+
+	d := getFakeCreateDriver(t, f)
+	defer os.RemoveAll(d.StorePath)
+
+	assert.Error(t, d.Create())
+
+	name := d.machineKeyPairName()
+	assert.True(t, strings.HasPrefix(name, "docker-machine-"+machineTestName+"-"))
+	if assert.NotNil(t, imported) {
+		assert.Equal(t, name, imported.Get("KeyPairName"))
+		assert.Equal(t, string(d.PublicKey), imported.Get("PublicKeyBody"))
+	}
+	if assert.NotNil(t, created) {
+		assert.Equal(t, name, created.Get("KeyPairName"))
+		assert.Empty(t, created.Get("Password"))
+	}
+	// The key pair is deleted by the rollback
+	if assert.NotNil(t, deleted) {
+		assert.Equal(t, `["`+name+`"]`, deleted.Get("KeyPairNames"))
+	}
+	assert.Empty(t, d.KeyPairName)
+}
+
+func TestCheckKeyPair(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(keyDir)
+
+	d.KeyPairName = "ops"
+	d.ExistingKeyPair = true
+	d.SSHPrivateKeyPath = filepath.Join(keyDir, "id_rsa")
+
+	// The private key is missing
+	assert.Error(t, d.checkKeyPair())
+
+	assert.NoError(t, ioutil.WriteFile(d.SSHPrivateKeyPath, []byte("key"), 0600))
+	response := describeKeyPairsResponse{}
+	f.respond("DescribeKeyPairs", &response)
+	assert.Error(t, d.checkKeyPair())
+
+	response.KeyPairs.KeyPair = []keyPair{{KeyPairName: "ops"}}
+	assert.NoError(t, d.checkKeyPair())
+}
+
+func TestRemoveKeyPair(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing key pair is kept
+	d.KeyPairName = "ops"
+	d.ExistingKeyPair = true
+	d.removeKeyPair()
+	assert.False(t, f.hasCalled("DeleteKeyPairs"))
+
+	d.ExistingKeyPair = false
+	d.removeKeyPair()
+	assert.True(t, f.hasCalled("DeleteKeyPairs"))
+	assert.Empty(t, d.KeyPairName)
+}
@@ -0,0 +1,219 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/provision"
+	"github.com/docker/machine/libmachine/ssh"
+)
+
+const (
+	prepareFdisk  = "fdisk"
+	prepareRoutes = "routes"
+	prepareKernel = "kernel"
+	prepareNone   = "none"
+)
+
+// The steps run by default, the fdisk step is skipped without data disk
+var defaultPrepareSteps = []string{prepareFdisk, prepareRoutes}
+
+// The time for the instance to go down after it is rebooted
+var rebootDelay = 30 * time.Second
+
+// osFamily is the family of Linux distributions sharing the package manager
+// and network configuration
+type osFamily string
+
+const (
+	osFamilyDebian = osFamily("debian")
+	osFamilyRHEL   = osFamily("rhel")
+)
+
+// prepareStep prepares the host over SSH after the instance is created
+type prepareStep struct {
+	name        string
+	description string
+	// run returns skipped if the step doesn't apply to the host
+	run func(d *Driver, sshClient ssh.Client, osr *provision.OsRelease) (skipped bool, err error)
+}
+
+var prepareSteps = []prepareStep{
+	{prepareFdisk, "partition, format and mount the data disks", (*Driver).prepareFdisk},
+	{prepareRoutes, "remove the route of 172.16.0.0/12 conflicting with Docker networks", (*Driver).prepareRoutes},
+	{prepareKernel, "upgrade the kernel and restart the instance", (*Driver).prepareKernel},
+}
+
+// parsePrepareSteps parses the comma separated steps of --aliyunecs-prepare,
+// the steps are always run in the order of prepareSteps
+func parsePrepareSteps(spec string) ([]string, error) {
+	selected := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == prepareNone {
+			continue
+		}
+		if findPrepareStep(name) == nil {
+			return nil, fmt.Errorf("unknown step %q, the valid steps are %s or %s", name, strings.Join(prepareStepNames(), ", "), prepareNone)
+		}
+		selected[name] = true
+	}
+
+	steps := []string{}
+	for _, step := range prepareSteps {
+		if selected[step.name] {
+			steps = append(steps, step.name)
+		}
+	}
+	return steps, nil
+}
+
+func prepareStepNames() []string {
+	names := []string{}
+	for _, step := range prepareSteps {
+		names = append(names, step.name)
+	}
+	return names
+}
+
+func findPrepareStep(name string) *prepareStep {
+	for i := range prepareSteps {
+		if prepareSteps[i].name == name {
+			return &prepareSteps[i]
+		}
+	}
+	return nil
+}
+
+// getOsRelease detects the OS of host with /etc/os-release
+func getOsRelease(sshClient ssh.Client) (*provision.OsRelease, error) {
+	output, err := sshClient.Output("cat /etc/os-release")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read /etc/os-release: %v: %s", err, output)
+	}
+	return provision.NewOsRelease([]byte(output))
+}
+
+// getOsFamily returns the family of the OS, CentOS and Alibaba Cloud Linux
+// are in the RHEL family
+func getOsFamily(osr *provision.OsRelease) (osFamily, error) {
+	for _, id := range append([]string{osr.ID}, strings.Fields(osr.IDLike)...) {
+		switch id {
+		case "debian", "ubuntu":
+			return osFamilyDebian, nil
+		case "rhel", "centos", "fedora", "alinux", "aliyun":
+			return osFamilyRHEL, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported OS %q", osr.PrettyName)
+}
+
+// prepareHost runs the selected steps, the failure of a step is reported and
+// the remaining steps still run
+func (d *Driver) prepareHost(sshClient ssh.Client) {
+	if len(d.PrepareSteps) == 0 {
+		return
+	}
+
+	osr, err := getOsRelease(sshClient)
+	if err != nil {
+		log.Warnf("%s | Failed to detect OS, skipping host preparation: %v", d.MachineName, err)
+		return
+	}
+	log.Infof("%s | Preparing host running %s ...", d.MachineName, osr.PrettyName)
+
+	for _, name := range d.PrepareSteps {
+		step := findPrepareStep(name)
+		if step == nil {
+			log.Warnf("%s | Unknown preparation step %s", d.MachineName, name)
+			continue
+		}
+		skipped, err := step.run(d, sshClient, osr)
+		switch {
+		case err != nil:
+			log.Warnf("%s | Preparation step %s failed: %v", d.MachineName, name, err)
+		case skipped:
+			log.Infof("%s | Preparation step %s skipped", d.MachineName, name)
+		default:
+			log.Infof("%s | Preparation step %s done: %s", d.MachineName, name, step.description)
+		}
+	}
+}
+
+func (d *Driver) prepareFdisk(sshClient ssh.Client, osr *provision.OsRelease) (bool, error) {
+	if !d.hasDataDisk() {
+		return true, nil
+	}
+	return false, d.autoFdisk(sshClient)
+}
+
+func (d *Driver) prepareRoutes(sshClient ssh.Client, osr *provision.OsRelease) (bool, error) {
+	family, err := getOsFamily(osr)
+	if err != nil {
+		return false, err
+	}
+
+	output, err := sshClient.Output("ip route del 172.16.0.0/12 2>/dev/null || true")
+	log.Debugf("%s | Delete route command err, output: %v: %s", d.MachineName, err, output)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete route: %v: %s", err, output)
+	}
+
+	// Remove the route persisted in the network configuration
+	var command string
+	switch family {
+	case osFamilyDebian:
+		command = "if [ -e /etc/network/interfaces ]; then sed -i '/^up route add -net 172.16.0.0 netmask 255.240.0.0 gw/d' /etc/network/interfaces; fi"
+	case osFamilyRHEL:
+		command = "for f in /etc/sysconfig/network-scripts/route-eth*; do [ -e $f ] && sed -i '/^172.16.0.0\\/12 via /d' $f; done; true"
+	}
+	output, err = sshClient.Output(command)
+	log.Debugf("%s | Fix route command err, output: %v: %s", d.MachineName, err, output)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove route from network configuration: %v: %s", err, output)
+	}
+	return false, nil
+}
+
+// kernelUpgradeCommand returns the command to upgrade the kernel, Ubuntu 14.04
+// is upgraded to the kernel 3.19 of LTS enablement stack
+func kernelUpgradeCommand(osr *provision.OsRelease) (string, error) {
+	family, err := getOsFamily(osr)
+	if err != nil {
+		return "", err
+	}
+
+	retry := "for i in 1 2 3 4 5; do %s && break || sleep 5; done"
+	switch {
+	case osr.ID == "ubuntu" && osr.VersionID == "14.04":
+		return fmt.Sprintf(retry, "apt-get update -y && apt-get install -y linux-generic-lts-vivid"), nil
+	case family == osFamilyDebian:
+		return fmt.Sprintf(retry, "apt-get update -y && DEBIAN_FRONTEND=noninteractive apt-get install -y --only-upgrade 'linux-image-*'"), nil
+	default:
+		return fmt.Sprintf(retry, "yum -y update kernel"), nil
+	}
+}
+
+func (d *Driver) prepareKernel(sshClient ssh.Client, osr *provision.OsRelease) (bool, error) {
+	command, err := kernelUpgradeCommand(osr)
+	if err != nil {
+		return false, err
+	}
+
+	log.Infof("%s | Upgrading kernel ...", d.MachineName)
+	output, err := sshClient.Output(command)
+	log.Debugf("%s | Upgrade kernel err, output: %v: %s", d.MachineName, err, output)
+	if err != nil {
+		return false, fmt.Errorf("failed to upgrade kernel: %v: %s", err, output)
+	}
+
+	log.Infof("%s | Restarting instance for kernel upgrade ...", d.MachineName)
+	if err := d.Restart(); err != nil {
+		return false, err
+	}
+	time.Sleep(rebootDelay)
+	return false, drivers.WaitForSSH(d)
+}
@@ -0,0 +1,126 @@
+package aliyunecs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/libmachine/provision"
+	"github.com/docker/machine/libmachine/ssh/sshtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ubuntuOsRelease = `NAME="Ubuntu"
+VERSION="14.04.5 LTS, Trusty Tahr"
+ID=ubuntu
+ID_LIKE=debian
+PRETTY_NAME="Ubuntu 14.04.5 LTS"
+VERSION_ID="14.04"
+`
+	centosOsRelease = `NAME="CentOS Linux"
+VERSION="7 (Core)"
+ID="centos"
+ID_LIKE="rhel fedora"
+VERSION_ID="7"
+PRETTY_NAME="CentOS Linux 7 (Core)"
+`
+	alinuxOsRelease = `NAME="Alibaba Cloud Linux"
+VERSION="3 (Soaring Falcon)"
+ID="alinux"
+ID_LIKE="rhel fedora centos anolis"
+VERSION_ID="3"
+PRETTY_NAME="Alibaba Cloud Linux 3 (Soaring Falcon)"
+`
+)
+
+func mustOsRelease(t *testing.T, content string) *provision.OsRelease {
+	osr, err := provision.NewOsRelease([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return osr
+}
+
+func TestParsePrepareSteps(t *testing.T) {
+	steps, err := parsePrepareSteps("kernel, fdisk,routes,kernel")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{prepareFdisk, prepareRoutes, prepareKernel}, steps)
+
+	steps, err = parsePrepareSteps("none")
+	assert.NoError(t, err)
+	assert.Empty(t, steps)
+
+	_, err = parsePrepareSteps("fdisk,reboot")
+	assert.Error(t, err)
+}
+
+func TestGetOsFamily(t *testing.T) {
+	for content, expected := range map[string]osFamily{
+		ubuntuOsRelease: osFamilyDebian,
+		centosOsRelease: osFamilyRHEL,
+		alinuxOsRelease: osFamilyRHEL,
+	} {
+		family, err := getOsFamily(mustOsRelease(t, content))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, family)
+	}
+
+	_, err := getOsFamily(mustOsRelease(t, "ID=alpine\n"))
+	assert.Error(t, err)
+}
+
+func TestKernelUpgradeCommand(t *testing.T) {
+	command, err := kernelUpgradeCommand(mustOsRelease(t, ubuntuOsRelease))
+	assert.NoError(t, err)
+	assert.Contains(t, command, "linux-generic-lts-vivid")
+
+	command, err = kernelUpgradeCommand(mustOsRelease(t, "ID=ubuntu\nVERSION_ID=\"16.04\"\n"))
+	assert.NoError(t, err)
+	assert.Contains(t, command, "--only-upgrade 'linux-image-*'")
+
+	command, err = kernelUpgradeCommand(mustOsRelease(t, alinuxOsRelease))
+	assert.NoError(t, err)
+	assert.Contains(t, command, "yum -y update kernel")
+}
+
+func TestPrepareHost(t *testing.T) {
+	d := NewDriver(machineTestName, "").(*Driver)
+	d.PrepareSteps = []string{prepareFdisk, prepareRoutes}
+
+	client := &sshtest.FakeClient{
+		Outputs: map[string]sshtest.CmdResult{
+			"cat /etc/os-release":                            {Out: centosOsRelease},
+			"ip route del 172.16.0.0/12 2>/dev/null || true": {Err: errors.New("exit status 255")},
+		},
+	}
+
+	// The fdisk step is skipped without data disk, and the failed routes
+	// step is only reported
+	d.prepareHost(client)
+
+	osr := mustOsRelease(t, centosOsRelease)
+	skipped, err := d.prepareFdisk(client, osr)
+	assert.True(t, skipped)
+	assert.NoError(t, err)
+
+	skipped, err = d.prepareRoutes(client, osr)
+	assert.False(t, skipped)
+	assert.Error(t, err)
+
+	delete(client.Outputs, "ip route del 172.16.0.0/12 2>/dev/null || true")
+	_, err = d.prepareRoutes(client, osr)
+	assert.NoError(t, err)
+}
+
+func TestSetConfigFromFlagsPrepare(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-prepare"] = "routes"
+	flags.Data["aliyunecs-upgrade-kernel"] = true
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.Equal(t, []string{prepareRoutes, prepareKernel}, d.PrepareSteps)
+
+	flags.Data["aliyunecs-prepare"] = "routes,swap"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
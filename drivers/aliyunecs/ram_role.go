@@ -0,0 +1,34 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/denverdino/aliyungo/common"
+)
+
+// The service principal to trust in the policy of RAM role for ECS instance
+const ecsServicePrincipal = "ecs.aliyuncs.com"
+
+// checkRamRole validates the RAM role exists and can be assumed by ECS, the
+// role attached is kept with the instance through stop and start
+func (d *Driver) checkRamRole() error {
+	role, err := d.getRole(d.RamRoleName)
+	if err != nil {
+		if e, ok := err.(*common.Error); ok && e.Code == "EntityNotExist.Role" {
+			return fmt.Errorf("%s | Invalid --aliyunecs-ram-role-name: RAM role %s does not exist", d.MachineName, d.RamRoleName)
+		}
+		return fmt.Errorf("%s | Failed to get RAM role %s: %v", d.MachineName, d.RamRoleName, err)
+	}
+
+	// The policy document may be URL encoded
+	policy := role.AssumeRolePolicyDocument
+	if decoded, err := url.QueryUnescape(policy); err == nil {
+		policy = decoded
+	}
+	if !strings.Contains(policy, ecsServicePrincipal) {
+		return fmt.Errorf("%s | Invalid --aliyunecs-ram-role-name: RAM role %s can not be assumed by ECS, it should trust the service %s", d.MachineName, d.RamRoleName, ecsServicePrincipal)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ecsTrustPolicy = `{"Statement":[{"Action":"sts:AssumeRole","Effect":"Allow","Principal":{"Service":["ecs.aliyuncs.com"]}}],"Version":"1"}`
+
+func TestCheckRamRole(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	policy := ecsTrustPolicy
+	f.handle("GetRole", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "oss-reader", params.Get("RoleName"))
+		return http.StatusOK, getRoleResponse{Role: ramRole{RoleName: "oss-reader", AssumeRolePolicyDocument: policy}}
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.RamRoleName = "oss-reader"
+	assert.NoError(t, d.checkRamRole())
+
+	policy = url.QueryEscape(ecsTrustPolicy)
+	assert.NoError(t, d.checkRamRole())
+
+	policy = `{"Statement":[{"Action":"sts:AssumeRole","Effect":"Allow","Principal":{"RAM":["acs:ram::1234:root"]}}],"Version":"1"}`
+	assert.Error(t, d.checkRamRole())
+
+	f.fail("GetRole", "EntityNotExist.Role")
+	err = d.checkRamRole()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "does not exist")
+	}
+}
+
+func TestAttachInstanceRamRole(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.handle("AttachInstanceRamRole", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "oss-reader", params.Get("RamRoleName"))
+		assert.Equal(t, `["i-test"]`, params.Get("InstanceIds"))
+		return http.StatusOK, struct{}{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, d.attachInstanceRamRole("i-test", "oss-reader"))
+	assert.True(t, f.hasCalled("AttachInstanceRamRole"))
+}
+
+func TestSetConfigFromFlagsRamRole(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-ram-role-name"] = "oss-reader"
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.Equal(t, "oss-reader", d.RamRoleName)
+}
@@ -0,0 +1,64 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+)
+
+const regionsCacheFile = "regions.json"
+
+var regionIdPattern = regexp.MustCompile(`^[a-z]+(-[a-z0-9]+)+$`)
+
+// validateECSRegion checks the form of region ID, the region is checked
+// against the regions offered by the API before creation
+func validateECSRegion(region string) (common.Region, error) {
+	if !regionIdPattern.MatchString(region) {
+		return "", errInvalidRegion
+	}
+	return common.Region(region), nil
+}
+
+// getRegions returns the regions offered by the API, they are cached in the
+// store directory
+func (d *Driver) getRegions() ([]ecs.RegionType, error) {
+	regions := []ecs.RegionType{}
+	if d.readCache(regionsCacheFile, &regions) {
+		return regions, nil
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	regions, err = client.DescribeRegions()
+	if err != nil {
+		return nil, fmt.Errorf("%s | Failed to describe regions: %v", d.MachineName, err)
+	}
+	d.writeCache(regionsCacheFile, regions)
+	return regions, nil
+}
+
+// checkRegion checks the region is offered by the API, it is skipped with the
+// custom API endpoint as before
+func (d *Driver) checkRegion() error {
+	if d.APIEndpoint != "" {
+		return nil
+	}
+
+	regions, err := d.getRegions()
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	for _, region := range regions {
+		if region.RegionId == d.Region {
+			return nil
+		}
+		ids = append(ids, string(region.RegionId))
+	}
+	return fmt.Errorf("%s | Invalid --aliyunecs-region %s: The value should be one of %s", d.MachineName, d.Region, strings.Join(ids, ", "))
+}
@@ -0,0 +1,76 @@
+package aliyunecs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeRegions(f *fakeECS) {
+	regions := ecs.DescribeRegionsResponse{}
+	regions.Regions.Region = []ecs.RegionType{
+		{RegionId: common.Hangzhou, LocalName: "China East 1"},
+		{RegionId: common.Region("ap-south-1"), LocalName: "India (Mumbai)"},
+	}
+	f.respond("DescribeRegions", regions)
+}
+
+func TestGetRegionsCached(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeRegions(f)
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.StorePath, err = ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d.StorePath)
+
+	regions, err := d.getRegions()
+	assert.NoError(t, err)
+	assert.Len(t, regions, 2)
+	_, err = os.Stat(d.cachePath(regionsCacheFile))
+	assert.NoError(t, err)
+
+	// The regions are read from the cache until it is expired
+	f.fail("DescribeRegions", "ServiceUnavailable")
+	regions, err = d.getRegions()
+	assert.NoError(t, err)
+	assert.Equal(t, "ap-south-1", string(regions[1].RegionId))
+
+	defer func(ttl time.Duration) { catalogueCacheTTL = ttl }(catalogueCacheTTL)
+	catalogueCacheTTL = 0
+	_, err = d.getRegions()
+	assert.Error(t, err)
+}
+
+func TestCheckRegion(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+
+	d := NewDriver(machineTestName, storePath).(*Driver)
+	d.writeCache(regionsCacheFile, []ecs.RegionType{{RegionId: common.Region("ap-south-1")}})
+
+	// The region launched after the static list is accepted
+	d.Region = common.Region("ap-south-1")
+	assert.NoError(t, d.checkRegion())
+
+	d.Region = common.Region("mars-north-1")
+	assert.Error(t, d.checkRegion())
+
+	// The region is not checked with custom API endpoint
+	d.APIEndpoint = "http://localhost"
+	assert.NoError(t, d.checkRegion())
+}
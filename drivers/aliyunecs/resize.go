@@ -0,0 +1,117 @@
+package aliyunecs
+
+import (
+	"fmt"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// Resize changes the instance type and the Internet bandwidth of instance,
+// the instance is restarted to change the instance type
+func (d *Driver) Resize(opts drivers.ResizeOptions) error {
+	if d.InstanceChargeType == PrePaid {
+		return fmt.Errorf("%s | PrePaid instance %s can not be resized", d.MachineName, d.InstanceId)
+	}
+
+	if opts.Bandwidth < 0 || opts.Bandwidth > maxInternetBandwidth {
+		return fmt.Errorf("%s | Invalid bandwidth %d: The value should be in 1 ~ %d", d.MachineName, opts.Bandwidth, maxInternetBandwidth)
+	}
+
+	instance, err := d.getInstance()
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe instance %s: %v", d.MachineName, d.InstanceId, err)
+	}
+
+	if opts.Size != "" && opts.Size != d.InstanceType {
+		if err := d.resizeInstanceType(instance, opts.Size); err != nil {
+			return err
+		}
+	}
+
+	if opts.Bandwidth > 0 && opts.Bandwidth != d.InternetMaxBandwidthOut {
+		if err := d.resizeBandwidth(instance, opts.Bandwidth); err != nil {
+			return err
+		}
+	}
+
+	instance, err = d.getInstance()
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe instance %s: %v", d.MachineName, d.InstanceId, err)
+	}
+	d.PrivateIPAddress = d.GetPrivateIP(instance)
+	d.IPAddress = d.getIP(instance)
+	return nil
+}
+
+func (d *Driver) resizeInstanceType(instance *ecs.InstanceAttributesType, instanceType string) error {
+	previous := d.InstanceType
+	d.InstanceType = instanceType
+	d.InstanceTypeAuto = false
+	if err := d.checkInstanceType(); err != nil {
+		d.InstanceType = previous
+		return err
+	}
+
+	running := instance.Status == ecs.Running
+	if running {
+		log.Infof("%s | Stopping instance %s ...", d.MachineName, d.InstanceId)
+		if err := d.Stop(); err != nil {
+			d.InstanceType = previous
+			return err
+		}
+	}
+
+	log.Infof("%s | Changing instance type of %s from %s to %s ...", d.MachineName, d.InstanceId, previous, instanceType)
+	args := modifyInstanceSpecArgs{
+		InstanceId:   d.InstanceId,
+		InstanceType: instanceType,
+	}
+	if err := d.modifyInstanceSpec(&args); err != nil {
+		d.InstanceType = previous
+		err = fmt.Errorf("%s | Failed to change instance type of %s: %v", d.MachineName, d.InstanceId, err)
+		if running {
+			// Bring the instance back as it was
+			if startErr := d.Start(); startErr != nil {
+				log.Errorf("%s | Failed to start instance %s: %v", d.MachineName, d.InstanceId, startErr)
+			}
+		}
+		return err
+	}
+
+	if running {
+		log.Infof("%s | Starting instance %s ...", d.MachineName, d.InstanceId)
+		if err := d.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resizeBandwidth changes the bandwidth of EIP for VPC instance, or the
+// Internet bandwidth of instance for classic network
+func (d *Driver) resizeBandwidth(instance *ecs.InstanceAttributesType, bandwidth int) error {
+	log.Infof("%s | Changing Internet bandwidth of %s from %d to %d Mbps ...", d.MachineName, d.InstanceId, d.InternetMaxBandwidthOut, bandwidth)
+
+	if allocationId := instance.EipAddress.AllocationId; allocationId != "" {
+		client, err := d.getClient()
+		if err != nil {
+			return err
+		}
+		if err := client.ModifyEipAddressAttribute(allocationId, bandwidth); err != nil {
+			return fmt.Errorf("%s | Failed to change bandwidth of EIP %s: %v", d.MachineName, allocationId, err)
+		}
+	} else {
+		args := modifyInstanceSpecArgs{
+			InstanceId:              d.InstanceId,
+			InternetMaxBandwidthOut: bandwidth,
+		}
+		if err := d.modifyInstanceSpec(&args); err != nil {
+			return fmt.Errorf("%s | Failed to change bandwidth of instance %s: %v", d.MachineName, d.InstanceId, err)
+		}
+	}
+
+	d.InternetMaxBandwidthOut = bandwidth
+	return nil
+}
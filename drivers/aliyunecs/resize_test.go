@@ -0,0 +1,76 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResize(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	instance := ecs.InstanceAttributesType{InstanceId: "i-test", Status: ecs.Stopped}
+	instance.EipAddress.AllocationId = "eip-test"
+	instance.EipAddress.IpAddress = "203.0.113.10"
+	f.respond("DescribeInstanceAttribute", instance)
+
+	instanceTypes := ecs.DescribeInstanceTypesResponse{}
+	instanceTypes.InstanceTypes.InstanceType = []ecs.InstanceTypeItemType{
+		{InstanceTypeId: defaultInstanceType, CpuCoreCount: 1, MemorySize: 1},
+		{InstanceTypeId: "ecs.n1.medium", CpuCoreCount: 2, MemorySize: 4},
+	}
+	f.respond("DescribeInstanceTypes", instanceTypes)
+
+	f.handle("ModifyInstanceSpec", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "i-test", params.Get("InstanceId"))
+		assert.Equal(t, "ecs.n1.medium", params.Get("InstanceType"))
+		return http.StatusOK, struct{}{}
+	})
+	f.handle("ModifyEipAddressAttribute", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "eip-test", params.Get("AllocationId"))
+		assert.Equal(t, "5", params.Get("Bandwidth"))
+		return http.StatusOK, struct{}{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+
+	assert.NoError(t, d.Resize(drivers.ResizeOptions{Size: "ecs.n1.medium", Bandwidth: 5}))
+	assert.Equal(t, "ecs.n1.medium", d.InstanceType)
+	assert.Equal(t, 5, d.InternetMaxBandwidthOut)
+	assert.Equal(t, "203.0.113.10", d.IPAddress)
+	assert.True(t, f.hasCalled("ModifyInstanceSpec"))
+	assert.True(t, f.hasCalled("ModifyEipAddressAttribute"))
+	assert.False(t, f.hasCalled("StopInstance"))
+}
+
+func TestResizeInvalidInstanceType(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.respond("DescribeInstanceAttribute", ecs.InstanceAttributesType{InstanceId: "i-test", Status: ecs.Running})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+
+	assert.Error(t, d.Resize(drivers.ResizeOptions{Size: "ecs.n1.huge"}))
+	assert.Equal(t, defaultInstanceType, d.InstanceType)
+	assert.False(t, f.hasCalled("StopInstance"))
+	assert.False(t, f.hasCalled("ModifyInstanceSpec"))
+}
+
+func TestResizePrePaidInstance(t *testing.T) {
+	d, err := getTestDriver()
+	assert.NoError(t, err)
+	defer cleanup()
+	d.InstanceChargeType = PrePaid
+
+	assert.Error(t, d.Resize(drivers.ResizeOptions{Size: "ecs.n1.medium"}))
+}
@@ -0,0 +1,128 @@
+package aliyunecs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// errKept is returned by the undo action which leaves the resource in place on
+// purpose, e.g. the PrePaid instance, the resources allocated before it are
+// kept for it as well
+var errKept = errors.New("resource is kept")
+
+type undoAction struct {
+	resource string
+	undo     func() error
+}
+
+// rollback records the resources allocated while creating the machine, so
+// that they can be released in reverse order if the creation fails
+type rollback struct {
+	actions []undoAction
+}
+
+// add records how to release the resource, it is a no-op out of Create
+func (r *rollback) add(resource string, undo func() error) {
+	if r == nil {
+		return
+	}
+	r.actions = append(r.actions, undoAction{resource: resource, undo: undo})
+}
+
+// run releases the recorded resources in reverse order, and returns the
+// resources which failed to release
+func (r *rollback) run(machineName string) []string {
+	failed := []string{}
+	for i := len(r.actions) - 1; i >= 0; i-- {
+		action := r.actions[i]
+		log.Infof("%s | Rolling back %s ...", machineName, action.resource)
+		err := action.undo()
+		if err == errKept {
+			kept := []string{}
+			for j := i - 1; j >= 0; j-- {
+				kept = append(kept, r.actions[j].resource)
+			}
+			if len(kept) > 0 {
+				log.Warnf("%s | Keeping %s used by %s", machineName, strings.Join(kept, ", "), action.resource)
+			}
+			break
+		}
+		if err != nil {
+			log.Errorf("%s | Failed to roll back %s: %v", machineName, action.resource, err)
+			failed = append(failed, action.resource)
+		}
+	}
+	r.actions = nil
+	return failed
+}
+
+// deleteInstance stops and deletes the instance created by Create
+func (d *Driver) deleteInstance(instanceId string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	if d.InstanceChargeType == PrePaid {
+		// PrePaid instance can not be deleted until it expires
+		if d.AutoRenew {
+			if err := d.modifyInstanceAutoRenewAttribute(instanceId, false); err != nil {
+				return err
+			}
+		}
+		log.Warnf("%s | PrePaid instance %s can not be deleted, it will be released by Aliyun on expiration", d.MachineName, instanceId)
+		return client.StopInstance(instanceId, true)
+	}
+
+	instance, err := client.DescribeInstanceAttribute(instanceId)
+	if err == nil && instance.Status != ecs.Stopped {
+		if err := client.StopInstance(instanceId, true); err != nil {
+			return err
+		}
+		if err := client.WaitForInstance(instanceId, ecs.Stopped, timeout); err != nil {
+			return err
+		}
+	}
+	return retry(func() error { return client.DeleteInstance(instanceId) })
+}
+
+// releaseEip unassociates the EIP from instance if needed and releases it
+func (d *Driver) releaseEip(allocationId string, instanceId string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	if err := d.unassociateEip(allocationId, instanceId); err != nil {
+		return err
+	}
+	return retry(func() error { return client.ReleaseEipAddress(allocationId) })
+}
+
+// unassociateEip unassociates the EIP from the instance if it is associated
+func (d *Driver) unassociateEip(allocationId string, instanceId string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	eips, _, err := client.DescribeEipAddresses(&ecs.DescribeEipAddressesArgs{
+		RegionId:     d.Region,
+		AllocationId: allocationId,
+	})
+	if err != nil {
+		return err
+	}
+	if len(eips) > 0 && eips[0].Status != ecs.EipStatusAvailable {
+		if err := client.UnassociateEipAddress(allocationId, instanceId); err != nil {
+			return err
+		}
+		if err := client.WaitForEip(d.Region, allocationId, ecs.EipStatusAvailable, 60); err != nil {
+			return fmt.Errorf("Failed to wait EIP %s available: %v", allocationId, err)
+		}
+	}
+	return nil
+}
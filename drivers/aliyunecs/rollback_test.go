@@ -0,0 +1,197 @@
+package aliyunecs
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackRun(t *testing.T) {
+	undone := []string{}
+	r := &rollback{}
+	for _, resource := range []string{"first", "second", "third"} {
+		resource := resource
+		r.add(resource, func() error {
+			undone = append(undone, resource)
+			if resource == "second" {
+				return errors.New("still in use")
+			}
+			return nil
+		})
+	}
+
+	failed := r.run("test")
+	assert.Equal(t, []string{"third", "second", "first"}, undone)
+	assert.Equal(t, []string{"second"}, failed)
+
+	// Recording out of Create is a no-op
+	var none *rollback
+	none.add("nothing", nil)
+}
+
+func TestRollbackRunKept(t *testing.T) {
+	undone := []string{}
+	r := &rollback{}
+	for _, resource := range []string{"key pair", "instance", "EIP"} {
+		resource := resource
+		r.add(resource, func() error {
+			undone = append(undone, resource)
+			if resource == "instance" {
+				return errKept
+			}
+			return nil
+		})
+	}
+
+	failed := r.run("test")
+	assert.Equal(t, []string{"EIP", "instance"}, undone)
+	assert.Empty(t, failed)
+}
+
+// newFakeCreateECS returns the fake ECS to create an instance in a new VPC,
+// the creation of instance fails
+func newFakeCreateECS() *fakeECS {
+	f := newFakeECS()
+
+	f.handle("DescribeVpcs", func(params url.Values) (int, interface{}) {
+		vpcs := ecs.DescribeVpcsResponse{}
+		if params.Get("VpcId") != "" {
+			vpcs.Vpcs.Vpc = []ecs.VpcSetType{{VpcId: "vpc-new", Status: ecs.VpcStatusAvailable}}
+		}
+		return http.StatusOK, vpcs
+	})
+	f.respond("CreateVpc", ecs.CreateVpcResponse{VpcId: "vpc-new", VRouterId: "vrt-new"})
+	f.handle("DescribeVSwitches", func(params url.Values) (int, interface{}) {
+		vswitches := ecs.DescribeVSwitchesResponse{}
+		if params.Get("VSwitchId") != "" {
+			vswitches.VSwitches.VSwitch = []ecs.VSwitchSetType{{VSwitchId: "vsw-new", Status: ecs.VSwitchStatusAvailable}}
+		}
+		return http.StatusOK, vswitches
+	})
+	f.respond("CreateVSwitch", ecs.CreateVSwitchResponse{VSwitchId: "vsw-new"})
+	f.respond("CreateSecurityGroup", ecs.CreateSecurityGroupResponse{SecurityGroupId: "sg-new"})
+	f.respond("DescribeSecurityGroupAttribute", ecs.DescribeSecurityGroupAttributeResponse{SecurityGroupId: "sg-new", VpcId: "vpc-new"})
+	f.fail("CreateInstance", "InvalidInstanceType.ValueNotSupported")
+	return f
+}
+
+// getFakeCreateDriver returns the driver with a store path to create the
+// SSH key in
+func getFakeCreateDriver(t *testing.T, f *fakeECS) *Driver {
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+
+	storePath, err := getTestStorePath()
+	assert.NoError(t, err)
+	d.StorePath = storePath
+	assert.NoError(t, os.MkdirAll(filepath.Dir(d.GetSSHKeyPath()), 0700))
+	return d
+}
+
+func TestCreateRollback(t *testing.T) {
+	f := newFakeCreateECS()
+	defer f.Close()
+
+	d := getFakeCreateDriver(t, f)
+	defer os.RemoveAll(d.StorePath)
+	d.CreateVPC = true
+
+	assert.Error(t, d.Create())
+	assert.True(t, f.hasCalled("CreateInstance"))
+
+	deleted := []string{}
+	for _, action := range f.called() {
+		switch action {
+		case "DeleteSecurityGroup", "DeleteVSwitch", "DeleteVpc":
+			deleted = append(deleted, action)
+		}
+	}
+	assert.Equal(t, []string{"DeleteSecurityGroup", "DeleteVSwitch", "DeleteVpc"}, deleted)
+	assert.Empty(t, d.SecurityGroupId)
+	assert.Empty(t, d.VSwitchId)
+	assert.Empty(t, d.VpcId)
+	assert.Nil(t, d.rollback)
+}
+
+func TestCreateKeepOnFailure(t *testing.T) {
+	f := newFakeCreateECS()
+	defer f.Close()
+
+	d := getFakeCreateDriver(t, f)
+	defer os.RemoveAll(d.StorePath)
+	d.CreateVPC = true
+	d.KeepOnFailure = true
+
+	assert.Error(t, d.Create())
+	assert.True(t, f.hasCalled("CreateInstance"))
+	assert.False(t, f.hasCalled("DeleteSecurityGroup"))
+	assert.False(t, f.hasCalled("DeleteVSwitch"))
+	assert.False(t, f.hasCalled("DeleteVpc"))
+	assert.Equal(t, "sg-new", d.SecurityGroupId)
+	assert.Equal(t, "vpc-new", d.VpcId)
+}
+
+func TestDeleteInstance(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.respond("DescribeInstanceAttribute", ecs.InstanceAttributesType{InstanceId: "i-test", Status: ecs.Stopped})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+
+	assert.NoError(t, d.deleteInstance("i-test"))
+	assert.Equal(t, []string{"DescribeInstanceAttribute", "DeleteInstance"}, f.called())
+}
+
+func TestCreateRollbackPrePaid(t *testing.T) {
+	f := newFakeCreateECS()
+	defer f.Close()
+	f.respond("CreateInstance", ecs.CreateInstanceResponse{InstanceId: "i-new"})
+	f.respond("DescribeInstanceAttribute", ecs.InstanceAttributesType{InstanceId: "i-new", Status: ecs.Stopped})
+	f.fail("AllocateEipAddress", "Forbidden.RAM")
+
+	d := getFakeCreateDriver(t, f)
+	defer os.RemoveAll(d.StorePath)
+	d.CreateVPC = true
+	d.InstanceChargeType = PrePaid
+
+	assert.Error(t, d.Create())
+	assert.True(t, f.hasCalled("StopInstance"))
+	assert.False(t, f.hasCalled("DeleteInstance"))
+	assert.False(t, f.hasCalled("DeleteKeyPairs"))
+	assert.False(t, f.hasCalled("DeleteSecurityGroup"))
+	assert.False(t, f.hasCalled("DeleteVSwitch"))
+	assert.False(t, f.hasCalled("DeleteVpc"))
+	assert.Equal(t, "i-new", d.InstanceId)
+	assert.Equal(t, "sg-new", d.SecurityGroupId)
+	assert.NotEmpty(t, d.KeyPairName)
+}
+
+func TestCreateAfterPreCreateCheck(t *testing.T) {
+	f := newFakeCreateECS()
+	defer f.Close()
+
+	d := getFakeCreateDriver(t, f)
+	defer os.RemoveAll(d.StorePath)
+	d.CreateVPC = true
+
+	assert.NoError(t, d.PreCreateCheck())
+	assert.Error(t, d.Create())
+
+	// The instance types are looked up by PreCreateCheck only
+	described := 0
+	for _, action := range f.called() {
+		if action == "DescribeInstanceTypes" {
+			described++
+		}
+	}
+	assert.Equal(t, 1, described)
+	assert.True(t, f.hasCalled("CreateInstance"))
+}
@@ -0,0 +1,170 @@
+package aliyunecs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	defaultRouteCIDRSize = 24
+	// The smallest subnet leaves room for the bridge and a container
+	maxRouteCIDRSize = 30
+	// The error of creating a route entry whose destination is routed already
+	routeEntryConflictCode = "InvalidCIDRBlock.Duplicate"
+)
+
+// parseRouteCIDRPool parses the IPv4 pool of --aliyunecs-route-cidr-pool to
+// allocate the subnets of the size from
+func parseRouteCIDRPool(pool string, size int) (*net.IPNet, error) {
+	_, ipnet, err := net.ParseCIDR(pool)
+	if err != nil || ipnet.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 CIDR %q", pool)
+	}
+	ones, _ := ipnet.Mask.Size()
+	if size < ones || size > maxRouteCIDRSize {
+		return nil, fmt.Errorf("the subnet size should be in %d ~ %d", ones, maxRouteCIDRSize)
+	}
+	return ipnet, nil
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
+
+// takesSubnet returns true if the routed CIDR takes the subnets of the size
+// in pool, which is the subnet routed to a host or a VSwitch in the pool. The
+// default route and the routes to the larger networks beyond the pool, e.g.
+// through a VPN gateway, cover the subnets without taking them.
+func takesSubnet(pool *net.IPNet, size int, routed *net.IPNet) bool {
+	ones, _ := routed.Mask.Size()
+	if ones == 0 {
+		return false
+	}
+	if ones >= size {
+		return true
+	}
+	poolOnes, _ := pool.Mask.Size()
+	return ones >= poolOnes && pool.Contains(routed.IP)
+}
+
+// freeSubnet returns the first subnet of the size in pool which overlaps
+// none of the used CIDRs taking the subnets
+func freeSubnet(pool *net.IPNet, size int, used []*net.IPNet) (*net.IPNet, error) {
+	ones, _ := pool.Mask.Size()
+	mask := net.CIDRMask(size, 32)
+	start := ipToUint32(pool.IP)
+	step := uint32(1) << uint(32-size)
+	count := uint64(1) << uint(size-ones)
+
+	taken := []*net.IPNet{}
+	for _, u := range used {
+		if takesSubnet(pool, size, u) {
+			taken = append(taken, u)
+		}
+	}
+
+	for i := uint64(0); i < count; i++ {
+		subnet := &net.IPNet{IP: uint32ToIP(start + uint32(i)*step), Mask: mask}
+		free := true
+		for _, u := range taken {
+			if overlaps(subnet, u) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return subnet, nil
+		}
+	}
+	return nil, fmt.Errorf("no free /%d subnet left in %s", size, pool)
+}
+
+func (d *Driver) describeRouteEntries(vrouterId string) ([]ecs.RouteEntrySetType, error) {
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	routeTables, _, err := client.DescribeRouteTables(&ecs.DescribeRouteTablesArgs{VRouterId: vrouterId})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ecs.RouteEntrySetType{}
+	for _, routeTable := range routeTables {
+		entries = append(entries, routeTable.RouteEntrys.RouteEntry...)
+	}
+	return entries, nil
+}
+
+// routeEntryNextHop returns the instance which the CIDR is routed to, empty
+// if the CIDR is not routed to an instance
+func (d *Driver) routeEntryNextHop(vrouterId string, cidr string) (string, error) {
+	entries, err := d.describeRouteEntries(vrouterId)
+	if err != nil {
+		return "", err
+	}
+	for _, routeEntry := range entries {
+		if routeEntry.DestinationCidrBlock == cidr {
+			return routeEntry.InstanceId, nil
+		}
+	}
+	return "", nil
+}
+
+// allocateRouteCIDR allocates the subnet of instance from the pool, which is
+// neither routed by the route table of VRouter nor known to be taken
+func (d *Driver) allocateRouteCIDR(vrouterId string, taken []*net.IPNet) error {
+	pool, err := parseRouteCIDRPool(d.RouteCIDRPool, d.RouteCIDRSize)
+	if err != nil {
+		return fmt.Errorf("%s | Invalid --aliyunecs-route-cidr-pool: %v", d.MachineName, err)
+	}
+
+	entries, err := d.describeRouteEntries(vrouterId)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe route tables: %v", d.MachineName, err)
+	}
+
+	used := append([]*net.IPNet{}, taken...)
+	for _, routeEntry := range entries {
+		if _, ipnet, err := net.ParseCIDR(routeEntry.DestinationCidrBlock); err == nil {
+			used = append(used, ipnet)
+		}
+	}
+
+	subnet, err := freeSubnet(pool, d.RouteCIDRSize, used)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to allocate route CIDR: %v", d.MachineName, err)
+	}
+	d.RouteCIDR = subnet.String()
+	log.Infof("%s | Allocated route CIDR %s from %s", d.MachineName, d.RouteCIDR, d.RouteCIDRPool)
+	return nil
+}
+
+// GetEngineFlags configures the Docker bridge with the subnet allocated from
+// the pool, so that the containers are routable across the hosts in VPC
+func (d *Driver) GetEngineFlags() ([]string, error) {
+	if d.RouteCIDRPool == "" || d.RouteCIDR == "" {
+		return nil, nil
+	}
+
+	_, subnet, err := net.ParseCIDR(d.RouteCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("%s | Invalid route CIDR %q: %v", d.MachineName, d.RouteCIDR, err)
+	}
+	ones, _ := subnet.Mask.Size()
+	bridge := uint32ToIP(ipToUint32(subnet.IP) + 1)
+	return []string{
+		fmt.Sprintf("bip=%s/%d", bridge, ones),
+		fmt.Sprintf("fixed-cidr=%s", subnet),
+	}, nil
+}
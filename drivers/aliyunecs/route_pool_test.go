@@ -0,0 +1,178 @@
+package aliyunecs
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRouteCIDRPool(t *testing.T) {
+	pool, err := parseRouteCIDRPool("172.16.0.0/12", 24)
+	assert.NoError(t, err)
+	assert.Equal(t, "172.16.0.0/12", pool.String())
+
+	for _, c := range []struct {
+		pool string
+		size int
+	}{
+		{"172.16.0.0", 24},
+		{"fd00::/64", 80},
+		{"172.16.0.0/12", 8},
+		{"172.16.0.0/12", 31},
+	} {
+		_, err := parseRouteCIDRPool(c.pool, c.size)
+		assert.Error(t, err, c.pool)
+	}
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	ipnets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		ipnets = append(ipnets, ipnet)
+	}
+	return ipnets
+}
+
+func TestFreeSubnet(t *testing.T) {
+	pool := parseCIDRs("172.16.0.0/22")[0]
+
+	subnet, err := freeSubnet(pool, 24, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "172.16.0.0/24", subnet.String())
+
+	// The VSwitch of 172.16.0.0/23 and the subnet of another host are taken
+	subnet, err = freeSubnet(pool, 24, parseCIDRs("172.16.0.0/23", "172.16.2.0/24", "10.0.0.0/8"))
+	assert.NoError(t, err)
+	assert.Equal(t, "172.16.3.0/24", subnet.String())
+
+	// The default route and the route to a larger network are not taken
+	subnet, err = freeSubnet(pool, 24, parseCIDRs("0.0.0.0/0", "172.16.0.0/12", "172.16.0.0/24"))
+	assert.NoError(t, err)
+	assert.Equal(t, "172.16.1.0/24", subnet.String())
+
+	_, err = freeSubnet(pool, 24, parseCIDRs("172.16.0.0/22"))
+	assert.Error(t, err)
+}
+
+func TestSetConfigFromFlagsRouteCIDRPool(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-route-cidr-pool"] = "172.16.0.0/12"
+	flags.Data["aliyunecs-route-cidr-size"] = 24
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	assert.Error(t, d.SetConfigFromFlags(flags))
+
+	flags.Data["aliyunecs-create-vpc"] = true
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.Equal(t, "172.16.0.0/12", d.RouteCIDRPool)
+	assert.Equal(t, 24, d.RouteCIDRSize)
+
+	flags.Data["aliyunecs-route-cidr"] = "172.16.1.0/24"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+
+	flags.Data["aliyunecs-route-cidr"] = ""
+	flags.Data["aliyunecs-route-cidr-size"] = 8
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestAddRouteEntryFromPool(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	vpcs := ecs.DescribeVpcsResponse{}
+	vpcs.Vpcs.Vpc = []ecs.VpcSetType{{VpcId: "vpc-test", VRouterId: "vrt-test"}}
+	f.respond("DescribeVpcs", vpcs)
+	vrouters := ecs.DescribeVRoutersResponse{}
+	vrouters.VRouters.VRouter = []ecs.VRouterSetType{{VRouterId: "vrt-test"}}
+	vrouters.VRouters.VRouter[0].RouteTableIds.RouteTableId = []string{"vtb-test"}
+	f.respond("DescribeVRouters", vrouters)
+
+	routeTable := ecs.RouteTableSetType{VRouterId: "vrt-test", RouteTableId: "vtb-test"}
+	routeTable.RouteEntrys.RouteEntry = []ecs.RouteEntrySetType{
+		{DestinationCidrBlock: "172.16.0.0/24", Type: ecs.RouteTableSystem},
+		{DestinationCidrBlock: "0.0.0.0/0", InstanceId: "i-nat"},
+	}
+	f.handle("DescribeRouteTables", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "vrt-test", params.Get("VRouterId"))
+		routeTables := ecs.DescribeRouteTablesResponse{}
+		routeTables.RouteTables.RouteTable = []ecs.RouteTableSetType{routeTable}
+		return http.StatusOK, routeTables
+	})
+
+	// The first subnet allocated is taken by the host created in parallel
+	created := []string{}
+	f.handle("CreateRouteEntry", func(params url.Values) (int, interface{}) {
+		cidr := params.Get("DestinationCidrBlock")
+		created = append(created, cidr)
+		if len(created) == 1 {
+			routeTable.RouteEntrys.RouteEntry = append(routeTable.RouteEntrys.RouteEntry, ecs.RouteEntrySetType{DestinationCidrBlock: cidr, InstanceId: "i-other"})
+			return http.StatusBadRequest, common.ErrorResponse{Code: routeEntryConflictCode}
+		}
+		assert.Equal(t, "i-test", params.Get("NextHopId"))
+		return http.StatusOK, struct{}{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+	d.RouteCIDRPool = "172.16.0.0/12"
+	d.RouteCIDRSize = 24
+
+	assert.NoError(t, d.addRouteEntry("vpc-test"))
+	assert.Equal(t, []string{"172.16.1.0/24", "172.16.2.0/24"}, created)
+	assert.Equal(t, "172.16.2.0/24", d.RouteCIDR)
+
+	flags, err := d.GetEngineFlags()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bip=172.16.2.1/24", "fixed-cidr=172.16.2.0/24"}, flags)
+}
+
+func TestAddRouteEntryFromPoolRetried(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	vpcs := ecs.DescribeVpcsResponse{}
+	vpcs.Vpcs.Vpc = []ecs.VpcSetType{{VpcId: "vpc-test", VRouterId: "vrt-test"}}
+	f.respond("DescribeVpcs", vpcs)
+	vrouters := ecs.DescribeVRoutersResponse{}
+	vrouters.VRouters.VRouter = []ecs.VRouterSetType{{VRouterId: "vrt-test"}}
+	vrouters.VRouters.VRouter[0].RouteTableIds.RouteTableId = []string{"vtb-test"}
+	f.respond("DescribeVRouters", vrouters)
+
+	// The entry is created by the request failed before
+	routeTable := ecs.RouteTableSetType{VRouterId: "vrt-test", RouteTableId: "vtb-test"}
+	routeTables := ecs.DescribeRouteTablesResponse{}
+	routeTables.RouteTables.RouteTable = []ecs.RouteTableSetType{routeTable}
+	f.handle("DescribeRouteTables", func(params url.Values) (int, interface{}) {
+		return http.StatusOK, routeTables
+	})
+	f.handle("CreateRouteEntry", func(params url.Values) (int, interface{}) {
+		routeTable.RouteEntrys.RouteEntry = []ecs.RouteEntrySetType{{DestinationCidrBlock: params.Get("DestinationCidrBlock"), InstanceId: "i-test"}}
+		routeTables.RouteTables.RouteTable = []ecs.RouteTableSetType{routeTable}
+		return http.StatusBadRequest, common.ErrorResponse{Code: routeEntryConflictCode}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+	d.RouteCIDRPool = "172.16.0.0/12"
+	d.RouteCIDRSize = 24
+
+	assert.NoError(t, d.addRouteEntry("vpc-test"))
+	assert.Equal(t, "172.16.0.0/24", d.RouteCIDR)
+}
+
+func TestGetEngineFlagsWithoutPool(t *testing.T) {
+	d := NewDriver(machineTestName, "").(*Driver)
+	d.RouteCIDR = "172.16.1.0/24"
+
+	flags, err := d.GetEngineFlags()
+	assert.NoError(t, err)
+	assert.Nil(t, flags)
+}
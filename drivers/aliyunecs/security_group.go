@@ -0,0 +1,207 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// parseOpenPort parses the value of --aliyunecs-open-port in the format of
+// port[-port][/protocol], the protocol is tcp by default
+func parseOpenPort(spec string) (IpPermission, error) {
+	perm := IpPermission{IpProtocol: ecs.IpProtocolTCP}
+
+	ports := spec
+	if i := strings.Index(spec, "/"); i >= 0 {
+		ports = spec[:i]
+		switch protocol := ecs.IpProtocol(strings.ToLower(spec[i+1:])); protocol {
+		case ecs.IpProtocolTCP, ecs.IpProtocolUDP:
+			perm.IpProtocol = protocol
+		default:
+			return perm, fmt.Errorf("unsupported protocol %q, should be tcp or udp", spec[i+1:])
+		}
+	}
+
+	portRange := strings.SplitN(ports, "-", 2)
+	if len(portRange) == 1 {
+		portRange = append(portRange, portRange[0])
+	}
+	from, err := strconv.Atoi(portRange[0])
+	if err != nil {
+		return perm, fmt.Errorf("invalid port %q", portRange[0])
+	}
+	to, err := strconv.Atoi(portRange[1])
+	if err != nil {
+		return perm, fmt.Errorf("invalid port %q", portRange[1])
+	}
+	if from < 1 || to > 65535 || from > to {
+		return perm, fmt.Errorf("invalid port range %d-%d", from, to)
+	}
+	perm.FromPort = from
+	perm.ToPort = to
+	return perm, nil
+}
+
+// parseSwarmPort sets the swarm port from the swarm host of swarm master
+func (d *Driver) parseSwarmPort() error {
+	if !d.isSwarmMaster() {
+		return nil
+	}
+	u, err := url.Parse(d.SwarmHost)
+	if err != nil {
+		return fmt.Errorf("error parsing swarm host: %s", err)
+	}
+
+	parts := strings.Split(u.Host, ":")
+	if len(parts) != 2 {
+		return fmt.Errorf("error parsing swarm host: missing port in %q", d.SwarmHost)
+	}
+	port, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+
+	swarmPort = port
+	return nil
+}
+
+// isRestricted returns true if the ingress is limited to the given ports or
+// source CIDR, otherwise all incoming traffic is accepted as before
+func (d *Driver) isRestricted() bool {
+	return len(d.OpenPorts) > 0 || (d.SourceCidr != "" && d.SourceCidr != ipRange)
+}
+
+func (d *Driver) sourceCidr() string {
+	if d.SourceCidr == "" {
+		return ipRange
+	}
+	return d.SourceCidr
+}
+
+// desiredPermissions returns the ingress permissions the machine requires
+func (d *Driver) desiredPermissions() []IpPermission {
+	cidr := d.sourceCidr()
+	ports := []int{22, dockerPort}
+	if d.isSwarmMaster() {
+		ports = append(ports, swarmPort)
+	}
+
+	perms := []IpPermission{}
+	for _, port := range ports {
+		perms = append(perms, IpPermission{
+			IpProtocol: ecs.IpProtocolTCP,
+			FromPort:   port,
+			ToPort:     port,
+			IpRange:    cidr,
+		})
+	}
+
+	for _, spec := range d.OpenPorts {
+		// The specs are validated in SetConfigFromFlags
+		perm, err := parseOpenPort(spec)
+		if err != nil {
+			log.Warnf("%s | Ignoring invalid open port %q: %v", d.MachineName, spec, err)
+			continue
+		}
+		perm.IpRange = cidr
+		perms = append(perms, perm)
+	}
+
+	// Never open all ports of the security group not created by the driver
+	if !d.isRestricted() && !d.ExistingSecurityGroup {
+		perms = append(perms, IpPermission{
+			IpProtocol: ecs.IpProtocolAll,
+			FromPort:   -1,
+			ToPort:     -1,
+			IpRange:    cidr,
+		})
+	}
+	return perms
+}
+
+func (p *IpPermission) portRange() string {
+	return fmt.Sprintf("%d/%d", p.FromPort, p.ToPort)
+}
+
+func isAcceptPolicy(policy ecs.PermissionPolicy) bool {
+	return policy == "" || strings.EqualFold(string(policy), string(ecs.PermissionPolicyAccept))
+}
+
+// sameRule returns true if the permission of security group covers the same
+// protocol and ports with p, regardless of the source
+func (p *IpPermission) sameRule(permission ecs.PermissionType) bool {
+	return strings.EqualFold(string(permission.IpProtocol), string(p.IpProtocol)) &&
+		permission.PortRange == p.portRange() &&
+		isAcceptPolicy(permission.Policy) &&
+		permission.SourceGroupId == ""
+}
+
+// matches returns true if the permission of security group is identical to p
+func (p *IpPermission) matches(permission ecs.PermissionType) bool {
+	return p.sameRule(permission) && permission.SourceCidrIp == p.IpRange
+}
+
+// stalePermissions returns the permissions of group which open the ports
+// managed by docker-machine to a source other than the configured one
+func (d *Driver) stalePermissions(group *ecs.DescribeSecurityGroupAttributeResponse) []ecs.PermissionType {
+	desired := d.desiredPermissions()
+	if d.isRestricted() {
+		// Accepting all incoming traffic defeats the restriction
+		desired = append(desired, IpPermission{IpProtocol: ecs.IpProtocolAll, FromPort: -1, ToPort: -1})
+	}
+
+	stale := []ecs.PermissionType{}
+	for _, permission := range group.Permissions.Permission {
+		for _, p := range desired {
+			if p.sameRule(permission) && permission.SourceCidrIp != p.IpRange {
+				stale = append(stale, permission)
+				break
+			}
+		}
+	}
+	return stale
+}
+
+// Reconcile brings the rules of security group back into compliance with
+// the configuration of machine
+func (d *Driver) Reconcile() error {
+	if d.SecurityGroupId == "" {
+		return nil
+	}
+	if d.AdoptedInstance {
+		log.Debugf("%s | Leaving security group %s of adopted instance as it is", d.MachineName, d.SecurityGroupId)
+		return nil
+	}
+	if err := d.parseSwarmPort(); err != nil {
+		return err
+	}
+
+	securityGroup, err := d.getSecurityGroup(d.SecurityGroupId)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe security group %s: %v", d.MachineName, d.SecurityGroupId, err)
+	}
+	log.Infof("%s | Reconciling rules of security group %s ...", d.MachineName, d.SecurityGroupId)
+	return d.authorizeSecurityGroup(securityGroup)
+}
+
+// cleanupSecurityGroup deletes the security group created for the machine
+// only, the shared one is kept for the other machines
+func (d *Driver) cleanupSecurityGroup(instanceId string) error {
+	if !d.MachineSecurityGroup || d.SecurityGroupId == "" {
+		return nil
+	}
+
+	if err := d.waitForInstanceDeleted(instanceId); err != nil {
+		return err
+	}
+	if err := retry(d.deleteSecurityGroup); err != nil {
+		return err
+	}
+	d.SecurityGroupId = ""
+	d.MachineSecurityGroup = false
+	return nil
+}
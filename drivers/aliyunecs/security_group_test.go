@@ -0,0 +1,194 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOpenPort(t *testing.T) {
+	perm, err := parseOpenPort("80")
+	assert.NoError(t, err)
+	assert.Equal(t, IpPermission{IpProtocol: ecs.IpProtocolTCP, FromPort: 80, ToPort: 80}, perm)
+
+	perm, err = parseOpenPort("8000-8010/UDP")
+	assert.NoError(t, err)
+	assert.Equal(t, IpPermission{IpProtocol: ecs.IpProtocolUDP, FromPort: 8000, ToPort: 8010}, perm)
+
+	for _, spec := range []string{"", "http", "80/icmp", "90-80", "0", "70000/tcp"} {
+		_, err = parseOpenPort(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSetConfigFromFlagsSecurityGroup(t *testing.T) {
+	d, err := getTestDriver()
+	assert.NoError(t, err)
+	defer cleanup()
+
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-open-port"] = []string{"80/tcp"}
+	flags.Data["aliyunecs-source-cidr"] = "10.0.0.0/8"
+	flags.Data["aliyunecs-security-group-id"] = "sg-test"
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.True(t, d.ExistingSecurityGroup)
+	assert.True(t, d.isRestricted())
+
+	flags.Data["aliyunecs-source-cidr"] = "10.0.0.0"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+
+	flags.Data["aliyunecs-source-cidr"] = ""
+	flags.Data["aliyunecs-open-port"] = []string{"80/sctp"}
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestConfigureSecurityGroupPermissionsRestricted(t *testing.T) {
+	d, err := getTestDriver()
+	assert.NoError(t, err)
+	defer cleanup()
+	d.OpenPorts = []string{"80", "53/udp"}
+	d.SourceCidr = "10.0.0.0/8"
+
+	securityGroup := ecs.DescribeSecurityGroupAttributeResponse{}
+	securityGroup.Permissions.Permission = []ecs.PermissionType{
+		{IpProtocol: "TCP", PortRange: "22/22", SourceCidrIp: "10.0.0.0/8", Policy: "Accept"},
+	}
+
+	perms := d.configureSecurityGroupPermissions(&securityGroup)
+	assert.Equal(t, []IpPermission{
+		{IpProtocol: ecs.IpProtocolTCP, FromPort: dockerPort, ToPort: dockerPort, IpRange: "10.0.0.0/8"},
+		{IpProtocol: ecs.IpProtocolTCP, FromPort: 80, ToPort: 80, IpRange: "10.0.0.0/8"},
+		{IpProtocol: ecs.IpProtocolUDP, FromPort: 53, ToPort: 53, IpRange: "10.0.0.0/8"},
+	}, perms)
+}
+
+func TestReconcile(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	group := ecs.DescribeSecurityGroupAttributeResponse{SecurityGroupId: "sg-test"}
+	group.Permissions.Permission = []ecs.PermissionType{
+		{IpProtocol: "TCP", PortRange: "22/22", SourceCidrIp: "0.0.0.0/0", Policy: "Accept"},
+		{IpProtocol: "TCP", PortRange: "2376/2376", SourceCidrIp: "10.0.0.0/8", Policy: "Accept"},
+		{IpProtocol: "ALL", PortRange: "-1/-1", SourceCidrIp: "0.0.0.0/0", Policy: "Accept"},
+		{IpProtocol: "TCP", PortRange: "22/22", SourceGroupId: "sg-other", Policy: "Accept"},
+	}
+	f.respond("DescribeSecurityGroupAttribute", group)
+
+	revoked := []string{}
+	f.handle("RevokeSecurityGroup", func(params url.Values) (int, interface{}) {
+		revoked = append(revoked, params.Get("IpProtocol")+" "+params.Get("PortRange"))
+		return http.StatusOK, struct{}{}
+	})
+	authorized := []string{}
+	f.handle("AuthorizeSecurityGroup", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "10.0.0.0/8", params.Get("SourceCidrIp"))
+		authorized = append(authorized, params.Get("IpProtocol")+" "+params.Get("PortRange"))
+		return http.StatusOK, struct{}{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SecurityGroupId = "sg-test"
+	d.MachineSecurityGroup = true
+	d.SourceCidr = "10.0.0.0/8"
+
+	assert.NoError(t, d.Reconcile())
+	assert.Equal(t, []string{"TCP 22/22", "ALL -1/-1"}, revoked)
+	assert.Equal(t, []string{"tcp 22/22"}, authorized)
+}
+
+func TestReconcileExistingSecurityGroup(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	group := ecs.DescribeSecurityGroupAttributeResponse{SecurityGroupId: "sg-test"}
+	group.Permissions.Permission = []ecs.PermissionType{
+		{IpProtocol: "TCP", PortRange: "22/22", SourceCidrIp: "0.0.0.0/0", Policy: "Accept"},
+	}
+	f.respond("DescribeSecurityGroupAttribute", group)
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SecurityGroupId = "sg-test"
+	d.ExistingSecurityGroup = true
+	d.SourceCidr = "10.0.0.0/8"
+
+	assert.NoError(t, d.Reconcile())
+	assert.False(t, f.hasCalled("RevokeSecurityGroup"))
+	assert.True(t, f.hasCalled("AuthorizeSecurityGroup"))
+}
+
+func TestReconcileSharedSecurityGroup(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	group := ecs.DescribeSecurityGroupAttributeResponse{SecurityGroupId: "sg-test"}
+	group.Permissions.Permission = []ecs.PermissionType{
+		{IpProtocol: "TCP", PortRange: "22/22", SourceCidrIp: "0.0.0.0/0", Policy: "Accept"},
+		{IpProtocol: "ALL", PortRange: "-1/-1", SourceCidrIp: "0.0.0.0/0", Policy: "Accept"},
+	}
+	f.respond("DescribeSecurityGroupAttribute", group)
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SecurityGroupId = "sg-test"
+	d.SourceCidr = "10.0.0.0/8"
+
+	assert.NoError(t, d.Reconcile())
+	assert.False(t, f.hasCalled("RevokeSecurityGroup"))
+	assert.True(t, f.hasCalled("AuthorizeSecurityGroup"))
+}
+
+func TestConfigureRestrictedSecurityGroup(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.handle("CreateSecurityGroup", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "docker-machine-"+machineTestName, params.Get("SecurityGroupName"))
+		return http.StatusOK, ecs.CreateSecurityGroupResponse{SecurityGroupId: "sg-machine"}
+	})
+	f.respond("DescribeSecurityGroupAttribute", ecs.DescribeSecurityGroupAttributeResponse{SecurityGroupId: "sg-machine", VpcId: "vpc-test"})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.VpcId = "vpc-test"
+	d.SourceCidr = "10.0.0.0/8"
+
+	assert.NoError(t, d.configureSecurityGroup("vpc-test", machineSecurityGroupName))
+	assert.False(t, f.hasCalled("DescribeSecurityGroups"))
+	assert.Equal(t, "sg-machine", d.SecurityGroupId)
+	assert.True(t, d.MachineSecurityGroup)
+
+	f.respond("DescribeInstances", ecs.DescribeInstancesResponse{})
+	assert.NoError(t, d.cleanupSecurityGroup("i-test"))
+	assert.True(t, f.hasCalled("DeleteSecurityGroup"))
+	assert.Empty(t, d.SecurityGroupId)
+}
+
+func TestExistingSecurityGroupNotOpenedToAll(t *testing.T) {
+	d, err := getTestDriver()
+	assert.NoError(t, err)
+	defer cleanup()
+	d.ExistingSecurityGroup = true
+
+	for _, p := range d.desiredPermissions() {
+		assert.NotEqual(t, ecs.IpProtocolAll, p.IpProtocol)
+	}
+}
+
+func TestReconcileAdoptedInstance(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SecurityGroupId = "sg-test"
+	d.AdoptedInstance = true
+
+	assert.NoError(t, d.Reconcile())
+	assert.Empty(t, f.called())
+}
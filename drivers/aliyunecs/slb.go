@@ -0,0 +1,204 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/denverdino/aliyungo/slb"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	defaultSLBWeight = 100
+	maxSLBWeight     = 100
+)
+
+// SLBAttachment is the SLB which the instance is added to as backend server
+type SLBAttachment struct {
+	LoadBalancerId string
+	Weight         int
+}
+
+// SLBListener is the TCP listener created on the attached SLBs
+type SLBListener struct {
+	Port        int
+	BackendPort int
+}
+
+// parseSLBAttachment parses the value of --aliyunecs-slb-id in the format of
+// id[:weight]
+func parseSLBAttachment(spec string) (SLBAttachment, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	attachment := SLBAttachment{
+		LoadBalancerId: strings.TrimSpace(parts[0]),
+		Weight:         defaultSLBWeight,
+	}
+	if attachment.LoadBalancerId == "" {
+		return attachment, fmt.Errorf("missing SLB id")
+	}
+	if len(parts) == 2 {
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 0 || weight > maxSLBWeight {
+			return attachment, fmt.Errorf("invalid weight %q, should be 0 ~ %d", parts[1], maxSLBWeight)
+		}
+		attachment.Weight = weight
+	}
+	return attachment, nil
+}
+
+// parseSLBListener parses the value of --aliyunecs-slb-listener in the format
+// of port[:backend-port]
+func parseSLBListener(spec string) (SLBListener, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+	ports := []int{}
+	for _, p := range parts {
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return SLBListener{}, fmt.Errorf("invalid port %q", p)
+		}
+		ports = append(ports, port)
+	}
+	return SLBListener{Port: ports[0], BackendPort: ports[1]}, nil
+}
+
+// slbAttachments returns the SLBs of machine, the machines created before
+// multiple SLBs were supported only have SLBID
+func (d *Driver) slbAttachments() []SLBAttachment {
+	if len(d.SLBAttachments) == 0 && d.SLBID != "" {
+		return []SLBAttachment{{LoadBalancerId: d.SLBID, Weight: defaultSLBWeight}}
+	}
+	return d.SLBAttachments
+}
+
+// checkSLBs validates the SLBs and the listeners to create on them
+func (d *Driver) checkSLBs() error {
+	client, err := d.getSLBClient()
+	if err != nil {
+		return err
+	}
+	for i, attachment := range d.slbAttachments() {
+		loadBalancer, err := client.DescribeLoadBalancerAttribute(attachment.LoadBalancerId)
+		if err != nil {
+			return fmt.Errorf("%s | Invalid --aliyunecs-slb-id %s: %v", d.MachineName, attachment.LoadBalancerId, err)
+		}
+		if i == 0 {
+			d.SLBIPAddress = loadBalancer.Address
+		}
+		for _, listener := range d.SLBListeners {
+			for _, existing := range loadBalancer.ListenerPortsAndProtocol.ListenerPortAndProtocol {
+				if existing.ListenerPort == listener.Port && !strings.EqualFold(existing.ListenerProtocol, string(slb.TCP)) {
+					return fmt.Errorf("%s | Port %d of SLB %s is used by %s listener", d.MachineName, listener.Port, attachment.LoadBalancerId, existing.ListenerProtocol)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// attachSLBs adds the instance to the backend servers of all SLBs and creates
+// the missing listeners
+func (d *Driver) attachSLBs(instanceId string) error {
+	client, err := d.getSLBClient()
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range d.slbAttachments() {
+		log.Infof("%s | Adding instance %s to SLB %s with weight %d ...", d.MachineName, instanceId, attachment.LoadBalancerId, attachment.Weight)
+		backendServers := []slb.BackendServerType{
+			{
+				ServerId: instanceId,
+				Weight:   attachment.Weight,
+			},
+		}
+		err := retry(func() error {
+			_, err := client.AddBackendServers(attachment.LoadBalancerId, backendServers)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s | Failed to add instance to SLB %s: %v", d.MachineName, attachment.LoadBalancerId, err)
+		}
+		loadBalancerId := attachment.LoadBalancerId
+		d.rollback.add("backend server of SLB "+loadBalancerId, func() error {
+			_, err := client.RemoveBackendServers(loadBalancerId, []string{instanceId})
+			return err
+		})
+
+		if err := d.createSLBListeners(attachment.LoadBalancerId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Driver) createSLBListeners(loadBalancerId string) error {
+	if len(d.SLBListeners) == 0 {
+		return nil
+	}
+
+	client, err := d.getSLBClient()
+	if err != nil {
+		return err
+	}
+	loadBalancer, err := client.DescribeLoadBalancerAttribute(loadBalancerId)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe SLB %s: %v", d.MachineName, loadBalancerId, err)
+	}
+
+	for _, listener := range d.SLBListeners {
+		exists := false
+		for _, port := range loadBalancer.ListenerPorts.ListenerPort {
+			if port == listener.Port {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			log.Debugf("%s | Listener %d of SLB %s exists already", d.MachineName, listener.Port, loadBalancerId)
+			continue
+		}
+
+		log.Infof("%s | Creating TCP listener %d => %d on SLB %s ...", d.MachineName, listener.Port, listener.BackendPort, loadBalancerId)
+		args := slb.CreateLoadBalancerTCPListenerArgs{
+			LoadBalancerId:    loadBalancerId,
+			ListenerPort:      listener.Port,
+			BackendServerPort: listener.BackendPort,
+			Bandwidth:         -1,
+		}
+		if err := client.CreateLoadBalancerTCPListener(&args); err != nil {
+			return fmt.Errorf("%s | Failed to create listener %d on SLB %s: %v", d.MachineName, listener.Port, loadBalancerId, err)
+		}
+		port := listener.Port
+		d.rollback.add(fmt.Sprintf("listener %d of SLB %s", port, loadBalancerId), func() error {
+			return client.DeleteLoadBalancerListener(loadBalancerId, port)
+		})
+		if err := client.StartLoadBalancerListener(loadBalancerId, listener.Port); err != nil {
+			return fmt.Errorf("%s | Failed to start listener %d on SLB %s: %v", d.MachineName, listener.Port, loadBalancerId, err)
+		}
+	}
+	return nil
+}
+
+// detachSLBs removes the instance from the backend servers of all SLBs, the
+// listeners are left as they are shared by the backend servers
+func (d *Driver) detachSLBs() {
+	attachments := d.slbAttachments()
+	if len(attachments) == 0 {
+		return
+	}
+	client, err := d.getSLBClient()
+	if err != nil {
+		log.Errorf("%s | Failed to remove instance %s from SLBs: %v", d.MachineName, d.InstanceId, err)
+		return
+	}
+	for _, attachment := range attachments {
+		log.Infof("%s | Removing instance %s from SLB %s ...", d.MachineName, d.InstanceId, attachment.LoadBalancerId)
+		if _, err := client.RemoveBackendServers(attachment.LoadBalancerId, []string{d.InstanceId}); err != nil {
+			log.Errorf("%s | Failed to remove instance %s from SLB %s: %v", d.MachineName, d.InstanceId, attachment.LoadBalancerId, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/slb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSLBAttachment(t *testing.T) {
+	attachment, err := parseSLBAttachment("lb-test")
+	assert.NoError(t, err)
+	assert.Equal(t, SLBAttachment{LoadBalancerId: "lb-test", Weight: 100}, attachment)
+
+	attachment, err = parseSLBAttachment("lb-test:20")
+	assert.NoError(t, err)
+	assert.Equal(t, SLBAttachment{LoadBalancerId: "lb-test", Weight: 20}, attachment)
+
+	for _, spec := range []string{"", ":20", "lb-test:heavy", "lb-test:101"} {
+		_, err = parseSLBAttachment(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseSLBListener(t *testing.T) {
+	listener, err := parseSLBListener("80")
+	assert.NoError(t, err)
+	assert.Equal(t, SLBListener{Port: 80, BackendPort: 80}, listener)
+
+	listener, err = parseSLBListener("80:8080")
+	assert.NoError(t, err)
+	assert.Equal(t, SLBListener{Port: 80, BackendPort: 8080}, listener)
+
+	for _, spec := range []string{"", "http", "80:0", "65536"} {
+		_, err = parseSLBListener(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSetConfigFromFlagsSLB(t *testing.T) {
+	d, err := getTestDriver()
+	assert.NoError(t, err)
+	defer cleanup()
+
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-api-endpoint"] = "http://localhost"
+	flags.Data["aliyunecs-slb-id"] = []string{"lb-a", "lb-b:50"}
+	flags.Data["aliyunecs-slb-listener"] = []string{"80:8080"}
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.Equal(t, []SLBAttachment{{"lb-a", 100}, {"lb-b", 50}}, d.SLBAttachments)
+	assert.Equal(t, "lb-a", d.SLBID)
+	assert.Equal(t, "http://localhost", d.SLBAPIEndpoint)
+
+	flags.Data["aliyunecs-slb-id"] = []string{}
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
+
+func TestAttachSLBs(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.handle("DescribeLoadBalancerAttribute", func(params url.Values) (int, interface{}) {
+		loadBalancer := slb.DescribeLoadBalancerAttributeResponse{}
+		loadBalancer.LoadBalancerId = params.Get("LoadBalancerId")
+		if loadBalancer.LoadBalancerId == "lb-a" {
+			loadBalancer.ListenerPorts.ListenerPort = []int{80}
+		}
+		return http.StatusOK, loadBalancer
+	})
+	weights := map[string]string{}
+	f.handle("AddBackendServers", func(params url.Values) (int, interface{}) {
+		weights[params.Get("LoadBalancerId")] = params.Get("BackendServers")
+		return http.StatusOK, slb.AddBackendServersResponse{}
+	})
+	listeners := []string{}
+	f.handle("CreateLoadBalancerTCPListener", func(params url.Values) (int, interface{}) {
+		listeners = append(listeners, params.Get("LoadBalancerId")+":"+params.Get("ListenerPort")+":"+params.Get("BackendServerPort"))
+		return http.StatusOK, struct{}{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.SLBAttachments = []SLBAttachment{{"lb-a", 100}, {"lb-b", 50}}
+	d.SLBListeners = []SLBListener{{Port: 80, BackendPort: 8080}}
+
+	assert.NoError(t, d.attachSLBs("i-test"))
+	assert.Equal(t, map[string]string{
+		"lb-a": `[{"ServerId":"i-test","Weight":100}]`,
+		"lb-b": `[{"ServerId":"i-test","Weight":50}]`,
+	}, weights)
+	assert.Equal(t, []string{"lb-b:80:8080"}, listeners)
+	assert.True(t, f.hasCalled("StartLoadBalancerListener"))
+}
+
+func TestRemoveDetachesSLBs(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	detached := []string{}
+	f.handle("RemoveBackendServers", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, `["i-test"]`, params.Get("BackendServers"))
+		detached = append(detached, params.Get("LoadBalancerId"))
+		return http.StatusOK, slb.RemoveBackendServersResponse{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+	d.SLBAttachments = []SLBAttachment{{"lb-a", 100}, {"lb-b", 50}}
+
+	assert.NoError(t, d.Remove())
+	assert.Equal(t, []string{"lb-a", "lb-b"}, detached)
+}
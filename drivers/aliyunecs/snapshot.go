@@ -0,0 +1,165 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const snapshotTimeFormat = "20060102150405"
+
+// getDataDisk returns the data disk mounted on /var/lib/docker
+func (d *Driver) getDataDisk() (*ecs.DiskItemType, error) {
+	if d.InstanceId == "" {
+		return nil, fmt.Errorf("%s | Unknown instance id", d.MachineName)
+	}
+
+	args := ecs.DescribeDisksArgs{
+		RegionId:   d.Region,
+		InstanceId: d.InstanceId,
+		DiskType:   ecs.DiskTypeAllData,
+	}
+	if d.DataDiskId != "" {
+		args.DiskIds = []string{d.DataDiskId}
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	disks, _, err := client.DescribeDisks(&args)
+	if err != nil {
+		return nil, err
+	}
+	if len(disks) == 0 {
+		return nil, fmt.Errorf("%s | No data disk found for instance %s", d.MachineName, d.InstanceId)
+	}
+	return &disks[0], nil
+}
+
+func (d *Driver) getSnapshot(snapshotId string) (*ecs.SnapshotType, error) {
+	args := ecs.DescribeSnapshotsArgs{
+		RegionId:    d.Region,
+		SnapshotIds: []string{snapshotId},
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	snapshots, _, err := client.DescribeSnapshots(&args)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("snapshot %s not found in region %s", snapshotId, d.Region)
+	}
+	return &snapshots[0], nil
+}
+
+// CreateSnapshot takes a snapshot of the data disk and waits for it to be ready
+func (d *Driver) CreateSnapshot(name string) (string, error) {
+	disk, err := d.getDataDisk()
+	if err != nil {
+		return "", err
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", d.MachineName, time.Now().Format(snapshotTimeFormat))
+	}
+
+	log.Infof("%s | Creating snapshot %s of data disk %s ...", d.MachineName, name, disk.DiskId)
+
+	client, err := d.getClient()
+	if err != nil {
+		return "", err
+	}
+	args := ecs.CreateSnapshotArgs{
+		DiskId:       disk.DiskId,
+		SnapshotName: name,
+		Description:  fmt.Sprintf("Data volume snapshot of %s", d.MachineName),
+		ClientToken:  client.GenerateClientToken(),
+	}
+	snapshotId, err := client.CreateSnapshot(&args)
+	if err != nil {
+		return "", fmt.Errorf("%s | Failed to create snapshot of disk %s: %v", d.MachineName, disk.DiskId, err)
+	}
+
+	err = client.WaitForSnapShotReady(d.Region, snapshotId, timeout)
+	if err != nil {
+		return snapshotId, fmt.Errorf("%s | Failed to wait snapshot %s ready: %v", d.MachineName, snapshotId, err)
+	}
+
+	log.Infof("%s | Created snapshot %s successfully", d.MachineName, snapshotId)
+	return snapshotId, nil
+}
+
+// ListSnapshots returns the snapshots of the data disk
+func (d *Driver) ListSnapshots() ([]drivers.Snapshot, error) {
+	disk, err := d.getDataDisk()
+	if err != nil {
+		return nil, err
+	}
+
+	args := ecs.DescribeSnapshotsArgs{
+		RegionId: d.Region,
+		DiskId:   disk.DiskId,
+	}
+
+	result := []drivers.Snapshot{}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		snapshots, pagination, err := client.DescribeSnapshots(&args)
+		if err != nil {
+			return nil, fmt.Errorf("%s | Failed to describe snapshots of disk %s: %v", d.MachineName, disk.DiskId, err)
+		}
+		for _, snapshot := range snapshots {
+			result = append(result, drivers.Snapshot{
+				ID:       snapshot.SnapshotId,
+				Name:     snapshot.SnapshotName,
+				Progress: snapshot.Progress,
+				Size:     snapshot.SourceDiskSize,
+				Created:  snapshot.CreationTime.String(),
+			})
+		}
+		nextPage := pagination.NextPage()
+		if nextPage == nil {
+			break
+		}
+		args.Pagination = *nextPage
+	}
+	return result, nil
+}
+
+// RemoveSnapshot deletes a snapshot of the data disk
+func (d *Driver) RemoveSnapshot(snapshotId string) error {
+	disk, err := d.getDataDisk()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := d.getSnapshot(snapshotId)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to delete the snapshots which are not taken from this machine
+	if snapshot.SourceDiskId != disk.DiskId {
+		return fmt.Errorf("%s | Snapshot %s is not taken from data disk %s", d.MachineName, snapshotId, disk.DiskId)
+	}
+
+	log.Infof("%s | Deleting snapshot %s ...", d.MachineName, snapshotId)
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteSnapshot(snapshotId); err != nil {
+		return fmt.Errorf("%s | Failed to delete snapshot %s: %v", d.MachineName, snapshotId, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeSnapshotECS() *fakeECS {
+	f := newFakeECS()
+
+	disks := ecs.DescribeDisksResponse{}
+	disks.Disks.Disk = []ecs.DiskItemType{{DiskId: "d-data", InstanceId: "i-test", Type: ecs.DiskTypeAllData}}
+	f.respond("DescribeDisks", disks)
+
+	f.respond("CreateSnapshot", ecs.CreateSnapshotResponse{SnapshotId: "s-new"})
+
+	f.handle("DescribeSnapshots", func(params url.Values) (int, interface{}) {
+		snapshots := ecs.DescribeSnapshotsResponse{
+			PaginationResult: common.PaginationResult{TotalCount: 2, PageNumber: 1, PageSize: 10},
+		}
+		snapshots.Snapshots.Snapshot = []ecs.SnapshotType{
+			{SnapshotId: "s-new", SourceDiskId: "d-data", SourceDiskSize: 40, Progress: "100%"},
+			{SnapshotId: "s-other", SourceDiskId: "d-other", SourceDiskSize: 100, Progress: "60%"},
+		}
+		if params.Get("SnapshotIds") == `["s-other"]` {
+			snapshots.Snapshots.Snapshot = snapshots.Snapshots.Snapshot[1:]
+		} else if params.Get("SnapshotIds") != "" {
+			snapshots.Snapshots.Snapshot = snapshots.Snapshots.Snapshot[:1]
+		}
+		return http.StatusOK, snapshots
+	})
+	return f
+}
+
+func TestSnapshotLifecycle(t *testing.T) {
+	f := newFakeSnapshotECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+
+	id, err := d.CreateSnapshot("")
+	assert.NoError(t, err)
+	assert.Equal(t, "s-new", id)
+
+	snapshots, err := d.ListSnapshots()
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, 40, snapshots[0].Size)
+
+	assert.NoError(t, d.RemoveSnapshot("s-new"))
+	assert.True(t, f.hasCalled("DeleteSnapshot"))
+}
+
+func TestRemoveSnapshotOfOtherDisk(t *testing.T) {
+	f := newFakeSnapshotECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.InstanceId = "i-test"
+
+	assert.Error(t, d.RemoveSnapshot("s-other"))
+	assert.False(t, f.hasCalled("DeleteSnapshot"))
+}
+
+func TestPreCreateCheckDataDiskSnapshot(t *testing.T) {
+	f := newFakeSnapshotECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+
+	d.DataDiskSnapshotId = "s-new"
+	assert.NoError(t, d.PreCreateCheck())
+
+	d.DiskSize = 20
+	assert.Error(t, d.PreCreateCheck())
+
+	d.DiskSize = 0
+	d.DataDiskSnapshotId = "s-other"
+	assert.Error(t, d.PreCreateCheck())
+}
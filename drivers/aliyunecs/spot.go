@@ -0,0 +1,97 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/docker/machine/libmachine/log"
+)
+
+type SpotStrategy string
+
+const (
+	NoSpot             = SpotStrategy("NoSpot")
+	SpotWithPriceLimit = SpotStrategy("SpotWithPriceLimit")
+	SpotAsPriceGo      = SpotStrategy("SpotAsPriceGo")
+)
+
+// The lock reason of spot instance being reclaimed by Aliyun
+const lockReasonRecycling = "Recycling"
+
+// setSpotFromFlags validates the spot strategy and the hourly price limit,
+// the strategy is SpotWithPriceLimit if only the price limit is specified
+func (d *Driver) setSpotFromFlags(strategy string, priceLimit string) error {
+	d.SpotStrategy = SpotStrategy(strategy)
+	d.SpotPriceLimit = 0
+
+	if priceLimit != "" {
+		price, err := strconv.ParseFloat(priceLimit, 64)
+		if err != nil || price <= 0 {
+			return fmt.Errorf("%s | Invalid --aliyunecs-spot-price-limit %s: The value should be a positive number", d.MachineName, priceLimit)
+		}
+		d.SpotPriceLimit = price
+		if d.SpotStrategy == "" {
+			d.SpotStrategy = SpotWithPriceLimit
+		}
+	}
+
+	switch d.SpotStrategy {
+	case "", NoSpot:
+		if d.SpotPriceLimit > 0 {
+			return fmt.Errorf("%s | The --aliyunecs-spot-price-limit can not be used with --aliyunecs-spot-strategy %s", d.MachineName, NoSpot)
+		}
+		return nil
+	case SpotWithPriceLimit:
+		if d.SpotPriceLimit == 0 {
+			return fmt.Errorf("%s | The --aliyunecs-spot-strategy %s requires --aliyunecs-spot-price-limit", d.MachineName, SpotWithPriceLimit)
+		}
+	case SpotAsPriceGo:
+		if d.SpotPriceLimit > 0 {
+			return fmt.Errorf("%s | The --aliyunecs-spot-price-limit can not be used with --aliyunecs-spot-strategy %s", d.MachineName, SpotAsPriceGo)
+		}
+	default:
+		return fmt.Errorf("%s | Invalid --aliyunecs-spot-strategy %s: The value should be %s, %s or %s", d.MachineName, d.SpotStrategy, NoSpot, SpotWithPriceLimit, SpotAsPriceGo)
+	}
+
+	if d.InstanceChargeType == PrePaid {
+		return fmt.Errorf("%s | Spot instance must be %s", d.MachineName, PostPaid)
+	}
+	return nil
+}
+
+func (d *Driver) isSpot() bool {
+	return d.SpotStrategy != "" && d.SpotStrategy != NoSpot
+}
+
+// setSpotArgs sets the spot strategy to create instance with
+func (d *Driver) setSpotArgs(args *createInstanceArgs) {
+	if !d.isSpot() {
+		return
+	}
+	args.SpotStrategy = d.SpotStrategy
+	if d.SpotStrategy == SpotWithPriceLimit {
+		args.SpotPriceLimit = &d.SpotPriceLimit
+	}
+	log.Infof("%s | Creating spot instance with strategy %s", d.MachineName, d.SpotStrategy)
+}
+
+// isRecycling returns true if the spot instance is being reclaimed
+func (instance *instanceAttributes) isRecycling() bool {
+	for _, lock := range instance.OperationLocks.LockReason {
+		if lock.LockReason == lockReasonRecycling {
+			return true
+		}
+	}
+	return false
+}
+
+// isReleased returns true if the spot instance has been released after it was
+// reclaimed, so that it can not be found any more
+func (d *Driver) isReleased(err error) bool {
+	if !d.isSpot() {
+		return false
+	}
+	e, ok := err.(*common.Error)
+	return ok && e.Code == "InvalidInstanceId.NotFound"
+}
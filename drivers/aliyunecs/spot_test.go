@@ -0,0 +1,115 @@
+package aliyunecs
+
+import (
+	"testing"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/denverdino/aliyungo/util"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSpotFromFlags(t *testing.T) {
+	var tests = []struct {
+		strategy   string
+		priceLimit string
+		chargeType InstanceChargeType
+		expected   SpotStrategy
+		price      float64
+		valid      bool
+	}{
+		{"", "", PostPaid, "", 0, true},
+		{"NoSpot", "", PostPaid, NoSpot, 0, true},
+		{"SpotAsPriceGo", "", PostPaid, SpotAsPriceGo, 0, true},
+		{"SpotWithPriceLimit", "0.5", PostPaid, SpotWithPriceLimit, 0.5, true},
+		{"", "0.25", PostPaid, SpotWithPriceLimit, 0.25, true},
+		{"SpotWithPriceLimit", "", PostPaid, "", 0, false},
+		{"SpotAsPriceGo", "0.5", PostPaid, "", 0, false},
+		{"NoSpot", "0.5", PostPaid, "", 0, false},
+		{"", "-1", PostPaid, "", 0, false},
+		{"", "cheap", PostPaid, "", 0, false},
+		{"Spot", "", PostPaid, "", 0, false},
+		{"SpotAsPriceGo", "", PrePaid, "", 0, false},
+	}
+
+	for _, test := range tests {
+		d := &Driver{BaseDriver: &drivers.BaseDriver{}, InstanceChargeType: test.chargeType}
+		err := d.setSpotFromFlags(test.strategy, test.priceLimit)
+		if !test.valid {
+			assert.Error(t, err, "%s %s", test.strategy, test.priceLimit)
+			continue
+		}
+		assert.NoError(t, err, "%s %s", test.strategy, test.priceLimit)
+		assert.Equal(t, test.expected, d.SpotStrategy)
+		assert.Equal(t, test.price, d.SpotPriceLimit)
+	}
+}
+
+func TestSetSpotArgs(t *testing.T) {
+	d := &Driver{BaseDriver: &drivers.BaseDriver{}, SpotStrategy: SpotWithPriceLimit, SpotPriceLimit: 0.5}
+	args := createInstanceArgs{}
+	d.setSpotArgs(&args)
+
+	query := util.ConvertToQueryValues(&args)
+	assert.Equal(t, "SpotWithPriceLimit", query.Get("SpotStrategy"))
+	assert.Equal(t, "0.5000", query.Get("SpotPriceLimit"))
+
+	d = &Driver{BaseDriver: &drivers.BaseDriver{}}
+	args = createInstanceArgs{}
+	d.setSpotArgs(&args)
+
+	query = util.ConvertToQueryValues(&args)
+	assert.Empty(t, query.Get("SpotStrategy"))
+	assert.Empty(t, query.Get("SpotPriceLimit"))
+}
+
+func TestGetStateOfRecyclingSpotInstance(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	instance := instanceAttributes{SpotStrategy: SpotAsPriceGo}
+	instance.InstanceId = "i-test"
+	instance.Status = ecs.Stopped
+	instance.OperationLocks.LockReason = []struct{ LockReason string }{{LockReason: lockReasonRecycling}}
+	f.respond("DescribeInstanceAttribute", instance)
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+	d.SpotStrategy = SpotAsPriceGo
+
+	s, err := d.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Reclaimed, s)
+
+	assert.Error(t, d.Start())
+	assert.False(t, f.hasCalled("StartInstance"))
+}
+
+func TestGetStateOfReleasedSpotInstance(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	f.fail("DescribeInstanceAttribute", "InvalidInstanceId.NotFound")
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+
+	s, err := d.GetState()
+	assert.Error(t, err)
+	assert.Equal(t, state.Error, s)
+
+	d.SpotStrategy = SpotAsPriceGo
+	s, err = d.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Reclaimed, s)
+
+	assert.NoError(t, d.Remove())
+	assert.True(t, f.hasCalled("DeleteInstance"))
+	assert.Empty(t, d.InstanceId)
+}
@@ -0,0 +1,76 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/denverdino/aliyungo/util"
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+const (
+	defaultStatsPeriod = 60
+	// Maximum number of samples returned by DescribeInstanceMonitorData
+	maxStatsSamples = 400
+)
+
+// GetStats returns the CPU, network and disk usage of instance reported by
+// CloudMonitor, the period is 60 (default), 600 or 3600 seconds
+func (d *Driver) GetStats(opts drivers.StatsOptions) ([]drivers.StatsSample, error) {
+	period := opts.Period
+	switch period {
+	case 0:
+		period = defaultStatsPeriod
+	case 60, 600, 3600:
+	default:
+		return nil, fmt.Errorf("%s | Invalid period %d: The value should be 60, 600 or 3600 seconds", d.MachineName, period)
+	}
+	if !opts.Start.Before(opts.End) {
+		return nil, fmt.Errorf("%s | Invalid time range %s ~ %s", d.MachineName, opts.Start, opts.End)
+	}
+
+	// The time range is split as the samples returned at once are limited
+	step := time.Duration(maxStatsSamples*period) * time.Second
+	samples := []drivers.StatsSample{}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	for start := opts.Start; start.Before(opts.End); start = start.Add(step) {
+		end := start.Add(step)
+		if end.After(opts.End) {
+			end = opts.End
+		}
+		args := ecs.DescribeInstanceMonitorDataArgs{
+			InstanceId: d.InstanceId,
+			StartTime:  util.NewISO6801Time(start),
+			EndTime:    util.NewISO6801Time(end),
+			Period:     period,
+		}
+		data, err := client.DescribeInstanceMonitorData(&args)
+		if err != nil {
+			return nil, fmt.Errorf("%s | Failed to describe monitor data of instance %s: %v", d.MachineName, d.InstanceId, err)
+		}
+		for _, item := range data {
+			samples = append(samples, statsSample(item, period))
+		}
+	}
+	return samples, nil
+}
+
+// statsSample converts the monitor data, in which the traffic is in kbits
+// during the period and the disk throughput is in bytes per second
+func statsSample(item ecs.InstanceMonitorDataType, period int) drivers.StatsSample {
+	bytesPerSecond := func(kbits int) float64 {
+		return float64(kbits) * 1000 / 8 / float64(period)
+	}
+	return drivers.StatsSample{
+		Time:       time.Time(item.TimeStamp),
+		CPU:        float64(item.CPU),
+		NetworkIn:  bytesPerSecond(item.InternetRX + item.IntranetRX),
+		NetworkOut: bytesPerSecond(item.InternetTX + item.IntranetTX),
+		DiskRead:   float64(item.BPSRead),
+		DiskWrite:  float64(item.BPSWrite),
+	}
+}
@@ -0,0 +1,72 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/denverdino/aliyungo/util"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStats(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	end := time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+	ranges := [][]string{}
+	f.handle("DescribeInstanceMonitorData", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "i-test", params.Get("InstanceId"))
+		assert.Equal(t, "60", params.Get("Period"))
+		ranges = append(ranges, []string{params.Get("StartTime"), params.Get("EndTime")})
+
+		response := ecs.DescribeInstanceMonitorDataResponse{}
+		response.MonitorData.InstanceMonitorData = []ecs.InstanceMonitorDataType{
+			{
+				InstanceId: "i-test",
+				CPU:        25,
+				InternetRX: 480,
+				IntranetRX: 480,
+				InternetTX: 96,
+				BPSRead:    4096,
+				BPSWrite:   8192,
+				TimeStamp:  util.NewISO6801Time(end),
+			},
+		}
+		return http.StatusOK, response
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+
+	samples, err := d.GetStats(drivers.StatsOptions{Start: end.Add(-10 * time.Hour), End: end})
+	assert.NoError(t, err)
+
+	// 400 samples of 60 seconds are returned at most at once
+	assert.Equal(t, [][]string{
+		{"2016-06-01T02:00:00Z", "2016-06-01T08:40:00Z"},
+		{"2016-06-01T08:40:00Z", "2016-06-01T12:00:00Z"},
+	}, ranges)
+	if assert.Len(t, samples, 2) {
+		assert.Equal(t, drivers.StatsSample{
+			Time:       end,
+			CPU:        25,
+			NetworkIn:  2000,
+			NetworkOut: 200,
+			DiskRead:   4096,
+			DiskWrite:  8192,
+		}, samples[0])
+	}
+
+	_, err = d.GetStats(drivers.StatsOptions{Start: end.Add(-time.Hour), End: end, Period: 120})
+	assert.Error(t, err)
+
+	_, err = d.GetStats(drivers.StatsOptions{Start: end, End: end.Add(-time.Hour)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package aliyunecs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// Maximum number of tags in one AddTags or RemoveTags request
+const maxTagsPerRequest = 5
+
+// parseTags parses the tags in the form key=value
+func parseTags(specs []string) (map[string]string, error) {
+	tags := map[string]string{}
+	for _, spec := range specs {
+		s := strings.Split(spec, "=")
+		if len(s) != 2 {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", spec)
+		}
+		k := strings.TrimSpace(s[0])
+		v := strings.TrimSpace(s[1])
+		if k == "" {
+			return nil, fmt.Errorf("invalid tag %q, the key is empty", spec)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+func sortedTagKeys(tags map[string]string) []string {
+	keys := []string{}
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tagBatches splits the tags into the batches accepted by a request
+func tagBatches(tags map[string]string) []map[string]string {
+	batches := []map[string]string{}
+	for i, k := range sortedTagKeys(tags) {
+		if i%maxTagsPerRequest == 0 {
+			batches = append(batches, map[string]string{})
+		}
+		batches[len(batches)-1][k] = tags[k]
+	}
+	return batches
+}
+
+func (d *Driver) addResourceTags(resourceType ecs.TagResourceType, resourceId string, tags map[string]string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	for _, batch := range tagBatches(tags) {
+		args := ecs.AddTagsArgs{
+			RegionId:     d.Region,
+			ResourceId:   resourceId,
+			ResourceType: resourceType,
+			Tag:          batch,
+		}
+		if err := client.AddTags(&args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Driver) removeResourceTags(resourceType ecs.TagResourceType, resourceId string, tags map[string]string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+	for _, batch := range tagBatches(tags) {
+		args := ecs.RemoveTagsArgs{
+			RegionId:     d.Region,
+			ResourceId:   resourceId,
+			ResourceType: resourceType,
+			Tag:          batch,
+		}
+		if err := client.RemoveTags(&args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taggedResources returns the data disks and the EIP of instance, which are
+// tagged together with the instance
+func (d *Driver) taggedResources() (diskIds []string, allocationId string, err error) {
+	disks, err := d.describeDataDisks()
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to describe data disks of instance %s: %v", d.InstanceId, err)
+	}
+	for _, disk := range disks {
+		diskIds = append(diskIds, disk.DiskId)
+	}
+
+	instance, err := d.getInstance()
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to describe instance %s: %v", d.InstanceId, err)
+	}
+	return diskIds, instance.EipAddress.AllocationId, nil
+}
+
+// addTags adds the tags to the instance, its data disks and EIP
+func (d *Driver) addTags(tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if err := d.addResourceTags(ecs.TagResourceInstance, d.InstanceId, tags); err != nil {
+		return fmt.Errorf("Failed to add tags to instance %s: %v", d.InstanceId, err)
+	}
+
+	diskIds, allocationId, err := d.taggedResources()
+	if err != nil {
+		return err
+	}
+	for _, diskId := range diskIds {
+		if err := d.addResourceTags(ecs.TagResourceDisk, diskId, tags); err != nil {
+			return fmt.Errorf("Failed to add tags to disk %s: %v", diskId, err)
+		}
+	}
+	if allocationId != "" {
+		if err := d.tagEip(allocationId, tags); err != nil {
+			return fmt.Errorf("Failed to add tags to EIP %s: %v", allocationId, err)
+		}
+	}
+	return nil
+}
+
+// removeTags removes the tags from the instance, its data disks and EIP
+func (d *Driver) removeTags(tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if err := d.removeResourceTags(ecs.TagResourceInstance, d.InstanceId, tags); err != nil {
+		return fmt.Errorf("Failed to remove tags from instance %s: %v", d.InstanceId, err)
+	}
+
+	diskIds, allocationId, err := d.taggedResources()
+	if err != nil {
+		return err
+	}
+	for _, diskId := range diskIds {
+		if err := d.removeResourceTags(ecs.TagResourceDisk, diskId, tags); err != nil {
+			return fmt.Errorf("Failed to remove tags from disk %s: %v", diskId, err)
+		}
+	}
+	if allocationId != "" {
+		if err := d.untagEip(allocationId, tags); err != nil {
+			return fmt.Errorf("Failed to remove tags from EIP %s: %v", allocationId, err)
+		}
+	}
+	return nil
+}
+
+// tagMachine adds the tags of --aliyunecs-tag to the resources created
+func (d *Driver) tagMachine() {
+	if len(d.Tags) == 0 {
+		return
+	}
+	log.Infof("%s | Adding tags %v to instance %s ...", d.MachineName, d.Tags, d.InstanceId)
+	if err := d.addTags(d.Tags); err != nil {
+		log.Warnf("%s | %v", d.MachineName, err)
+	}
+}
+
+// GetLabels returns the tags of instance
+func (d *Driver) GetLabels() (map[string]string, error) {
+	args := ecs.DescribeTagsArgs{
+		RegionId:     d.Region,
+		ResourceType: ecs.TagResourceInstance,
+		ResourceId:   d.InstanceId,
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	tags, _, err := client.DescribeTags(&args)
+	if err != nil {
+		return nil, fmt.Errorf("%s | Failed to describe tags of instance %s: %v", d.MachineName, d.InstanceId, err)
+	}
+
+	labels := map[string]string{}
+	for _, tag := range tags {
+		labels[tag.TagKey] = tag.TagValue
+	}
+	return labels, nil
+}
+
+// SetLabels updates the tags of instance, its data disks and EIP
+func (d *Driver) SetLabels(opts drivers.LabelOptions) error {
+	labels, err := d.GetLabels()
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]string{}
+	for _, k := range opts.Remove {
+		if v, ok := labels[k]; ok {
+			removed[k] = v
+			delete(labels, k)
+		}
+	}
+	if len(removed) > 0 {
+		log.Infof("%s | Removing tags %v from instance %s ...", d.MachineName, removed, d.InstanceId)
+		if err := d.removeTags(removed); err != nil {
+			return fmt.Errorf("%s | %v", d.MachineName, err)
+		}
+	}
+
+	if len(opts.Set) > 0 {
+		log.Infof("%s | Adding tags %v to instance %s ...", d.MachineName, opts.Set, d.InstanceId)
+		if err := d.addTags(opts.Set); err != nil {
+			return fmt.Errorf("%s | %v", d.MachineName, err)
+		}
+		for k, v := range opts.Set {
+			labels[k] = v
+		}
+	}
+
+	d.Tags = labels
+	return nil
+}
+
+// findInstanceByTags returns the only instance with all of the tags
+func (d *Driver) findInstanceByTags(tags map[string]string) (string, error) {
+	args := ecs.DescribeResourceByTagsArgs{
+		RegionId:     d.Region,
+		ResourceType: ecs.TagResourceInstance,
+		Tag:          tags,
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return "", err
+	}
+	resources, _, err := client.DescribeResourceByTags(&args)
+	if err != nil {
+		return "", fmt.Errorf("%s | Failed to find instance by tags %v: %v", d.MachineName, tags, err)
+	}
+
+	ids := []string{}
+	for _, resource := range resources {
+		ids = append(ids, resource.ResourceId)
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%s | No instance with tags %v in region %s", d.MachineName, tags, d.Region)
+	case 1:
+		log.Infof("%s | Found instance %s with tags %v", d.MachineName, ids[0], tags)
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("%s | More than one instance with tags %v: %s", d.MachineName, tags, strings.Join(ids, ", "))
+}
@@ -0,0 +1,156 @@
+package aliyunecs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags([]string{"env=prod", " team = infra "})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "infra"}, tags)
+
+	for _, spec := range []string{"env", "a=b=c", "=prod"} {
+		_, err := parseTags([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestTagBatches(t *testing.T) {
+	tags := map[string]string{}
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		tags[k] = k
+	}
+
+	batches := tagBatches(tags)
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], maxTagsPerRequest)
+	assert.Equal(t, map[string]string{"f": "f", "g": "g"}, batches[1])
+}
+
+func fakeTaggedInstance(f *fakeECS) {
+	tags := ecs.DescribeTagsResponse{}
+	tags.Tags.Tag = []ecs.TagItemType{
+		{TagKey: "env", TagValue: "dev"},
+		{TagKey: "team", TagValue: "infra"},
+	}
+	f.respond("DescribeTags", tags)
+
+	disks := ecs.DescribeDisksResponse{}
+	disks.Disks.Disk = []ecs.DiskItemType{{DiskId: "d-data", Device: "/dev/xvdb", Type: ecs.DiskTypeAllData}}
+	f.respond("DescribeDisks", disks)
+
+	instance := ecs.InstanceAttributesType{InstanceId: "i-test", Status: ecs.Running}
+	instance.EipAddress.AllocationId = "eip-test"
+	f.respond("DescribeInstanceAttribute", instance)
+}
+
+func TestGetLabels(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeTaggedInstance(f)
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+
+	labels, err := d.GetLabels()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "dev", "team": "infra"}, labels)
+}
+
+func TestSetLabels(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+	fakeTaggedInstance(f)
+
+	added := map[string]string{}
+	f.handle("AddTags", func(params url.Values) (int, interface{}) {
+		added[params.Get("ResourceId")] = params.Get("Tag.1.Key") + "=" + params.Get("Tag.1.Value")
+		return http.StatusOK, struct{}{}
+	})
+	removed := map[string]string{}
+	f.handle("RemoveTags", func(params url.Values) (int, interface{}) {
+		removed[params.Get("ResourceId")] = params.Get("Tag.1.Key") + "=" + params.Get("Tag.1.Value")
+		return http.StatusOK, struct{}{}
+	})
+	eipTags := []url.Values{}
+	f.handle("TagResources", func(params url.Values) (int, interface{}) {
+		eipTags = append(eipTags, params)
+		return http.StatusOK, struct{}{}
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.InstanceId = "i-test"
+
+	err = d.SetLabels(drivers.LabelOptions{
+		Set:    map[string]string{"env": "prod"},
+		Remove: []string{"team", "missing"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"i-test": "team=infra", "d-data": "team=infra"}, removed)
+	assert.Equal(t, map[string]string{"i-test": "env=prod", "d-data": "env=prod"}, added)
+	assert.True(t, f.hasCalled("UntagResources"))
+	if assert.Len(t, eipTags, 1) {
+		assert.Equal(t, "EIP", eipTags[0].Get("ResourceType"))
+		assert.Equal(t, "eip-test", eipTags[0].Get("ResourceId.1"))
+		assert.Equal(t, "env", eipTags[0].Get("Tag.1.Key"))
+	}
+	assert.Equal(t, map[string]string{"env": "prod"}, d.Tags)
+}
+
+func TestFindInstanceByTags(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	resources := ecs.DescribeResourceByTagsResponse{}
+	resources.Resources.Resource = []ecs.ResourceItemType{{ResourceId: "i-tagged", ResourceType: ecs.TagResourceInstance}}
+	f.handle("DescribeResourceByTags", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "instance", params.Get("ResourceType"))
+		assert.Equal(t, "role", params.Get("Tag.1.Key"))
+		assert.Equal(t, "worker", params.Get("Tag.1.Value"))
+		return http.StatusOK, resources
+	})
+
+	d, err := getFakeECSDriver(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := d.findInstanceByTags(map[string]string{"role": "worker"})
+	assert.NoError(t, err)
+	assert.Equal(t, "i-tagged", id)
+
+	resources.Resources.Resource = append(resources.Resources.Resource, ecs.ResourceItemType{ResourceId: "i-other"})
+	_, err = d.findInstanceByTags(map[string]string{"role": "worker"})
+	assert.Error(t, err)
+
+	resources.Resources.Resource = nil
+	_, err = d.findInstanceByTags(map[string]string{"role": "worker"})
+	assert.Error(t, err)
+}
+
+func TestSetConfigFromFlagsImportTags(t *testing.T) {
+	flags := getDefaultTestDriverFlags()
+	flags.Data["aliyunecs-ssh-password"] = "secret"
+	flags.Data["aliyunecs-import-tag"] = []string{"role=worker"}
+
+	d := NewDriver(machineTestName, "").(*Driver)
+	assert.NoError(t, d.SetConfigFromFlags(flags))
+	assert.True(t, d.AdoptedInstance)
+	assert.Equal(t, map[string]string{"role": "worker"}, d.ImportTags)
+
+	flags.Data["aliyunecs-instance-id"] = "i-existing"
+	assert.Error(t, d.SetConfigFromFlags(flags))
+}
@@ -2,9 +2,12 @@ package aliyunecs
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"errors"
-
-	"github.com/denverdino/aliyungo/common"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
 )
 
 var (
@@ -15,19 +18,12 @@ var (
 	errComplete       = errors.New("Complete")
 )
 
+// Maximum size of the base64 encoded user data
+const maxUserDataSize = 16 * 1024
+
 const defaultUbuntuImageID = "ubuntu1404_64_20G_aliaegis_20150325.vhd"
 const defaultUbuntuImagePrefix = "ubuntu1404_64_20G_"
 
-func validateECSRegion(region string) (common.Region, error) {
-	for _, v := range common.ValidRegions {
-		if v == common.Region(region) {
-			return v, nil
-		}
-	}
-
-	return "", errInvalidRegion
-}
-
 const dictionary = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 const paswordLen = 16
 
@@ -39,3 +35,39 @@ func randomPassword() string {
 	}
 	return string(bytes)
 }
+
+// isValidPeriod checks the subscription period (in months) of PrePaid instance
+func isValidPeriod(period int) bool {
+	switch period {
+	case 1, 2, 3, 4, 5, 6, 7, 8, 9, 12, 24, 36:
+		return true
+	}
+	return false
+}
+
+// readUserData returns the content of user data file, or the value itself if
+// it is not a path of existing file
+func readUserData(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	userData := value
+	if _, err := os.Stat(value); err == nil {
+		buf, err := ioutil.ReadFile(value)
+		if err != nil {
+			return "", err
+		}
+		userData = string(buf)
+	}
+
+	if base64.StdEncoding.EncodedLen(len(userData)) > maxUserDataSize {
+		return "", fmt.Errorf("the user data should be no more than %d bytes after base64 encoding", maxUserDataSize)
+	}
+	return userData, nil
+}
+
+// overlaps returns true if the two CIDR blocks share any address
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
@@ -0,0 +1,353 @@
+package aliyunecs
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+const machineVpcName = "docker-machine"
+
+// configureVPC looks up or creates the VPC and VSwitch managed by docker-machine,
+// only the ones created by the machine are deleted on removal
+func (d *Driver) configureVPC() error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	vpc, err := d.findManagedVpc()
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe VPCs in region %s: %v", d.MachineName, d.Region, err)
+	}
+
+	if vpc == nil {
+		log.Infof("%s | Creating VPC %s (%s) in region %s ...", d.MachineName, machineVpcName, vpcCidrBlock, d.Region)
+		args := ecs.CreateVpcArgs{
+			RegionId:    d.Region,
+			CidrBlock:   vpcCidrBlock,
+			VpcName:     machineVpcName,
+			Description: "Docker Machine",
+			ClientToken: client.GenerateClientToken(),
+		}
+		resp, err := client.CreateVpc(&args)
+		if err != nil {
+			return fmt.Errorf("%s | Failed to create VPC: %v", d.MachineName, err)
+		}
+		d.rollback.add("VPC "+resp.VpcId, func() error {
+			err := retry(func() error { return client.DeleteVpc(resp.VpcId) })
+			if err == nil {
+				d.VpcId = ""
+				d.VpcOwned = false
+			}
+			return err
+		})
+		if err := client.WaitForVpcAvailable(d.Region, resp.VpcId, timeout); err != nil {
+			return fmt.Errorf("%s | Failed to wait VPC %s available: %v", d.MachineName, resp.VpcId, err)
+		}
+		log.Infof("%s | Created VPC %s with VRouter %s", d.MachineName, resp.VpcId, resp.VRouterId)
+		d.VpcId = resp.VpcId
+		d.VpcOwned = true
+	} else {
+		log.Infof("%s | Using existing VPC %s", d.MachineName, vpc.VpcId)
+		d.VpcId = vpc.VpcId
+		d.VpcOwned = false
+	}
+
+	if d.Zone == "" {
+		zone, err := d.findZone(ecs.ResourceTypeVSwitch)
+		if err != nil {
+			return err
+		}
+		d.Zone = zone
+	}
+
+	vswitches, err := d.describeVSwitches(d.VpcId)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to describe VSwitches of VPC %s: %v", d.MachineName, d.VpcId, err)
+	}
+
+	usedCidrBlocks := []string{}
+	for _, vswitch := range vswitches {
+		if vswitch.ZoneId == d.Zone && vswitch.VSwitchName == machineVpcName {
+			log.Infof("%s | Using existing VSwitch %s in zone %s", d.MachineName, vswitch.VSwitchId, d.Zone)
+			d.VSwitchId = vswitch.VSwitchId
+			d.VSwitchOwned = false
+			return nil
+		}
+		usedCidrBlocks = append(usedCidrBlocks, vswitch.CidrBlock)
+	}
+
+	cidrBlock, err := nextVSwitchCidrBlock(usedCidrBlocks)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to allocate CIDR block for VSwitch in VPC %s: %v", d.MachineName, d.VpcId, err)
+	}
+
+	log.Infof("%s | Creating VSwitch %s (%s) in zone %s ...", d.MachineName, machineVpcName, cidrBlock, d.Zone)
+	args := ecs.CreateVSwitchArgs{
+		ZoneId:      d.Zone,
+		CidrBlock:   cidrBlock,
+		VpcId:       d.VpcId,
+		VSwitchName: machineVpcName,
+		Description: "Docker Machine",
+		ClientToken: client.GenerateClientToken(),
+	}
+	vswitchId, err := client.CreateVSwitch(&args)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to create VSwitch: %v", d.MachineName, err)
+	}
+	d.rollback.add("VSwitch "+vswitchId, func() error {
+		err := retry(func() error { return client.DeleteVSwitch(vswitchId) })
+		if err == nil {
+			d.VSwitchId = ""
+			d.VSwitchOwned = false
+		}
+		return err
+	})
+	if err := client.WaitForVSwitchAvailable(d.VpcId, vswitchId, timeout); err != nil {
+		return fmt.Errorf("%s | Failed to wait VSwitch %s available: %v", d.MachineName, vswitchId, err)
+	}
+	d.VSwitchId = vswitchId
+	d.VSwitchOwned = true
+	return nil
+}
+
+func (d *Driver) findManagedVpc() (*ecs.VpcSetType, error) {
+	args := ecs.DescribeVpcsArgs{
+		RegionId: d.Region,
+	}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		vpcs, pagination, err := client.DescribeVpcs(&args)
+		if err != nil {
+			return nil, err
+		}
+		for _, vpc := range vpcs {
+			if vpc.VpcName == machineVpcName {
+				return &vpc, nil
+			}
+		}
+		nextPage := pagination.NextPage()
+		if nextPage == nil {
+			break
+		}
+		args.Pagination = *nextPage
+	}
+	return nil, nil
+}
+
+func (d *Driver) describeVSwitches(vpcId string) ([]ecs.VSwitchSetType, error) {
+	args := ecs.DescribeVSwitchesArgs{
+		VpcId: vpcId,
+	}
+	result := []ecs.VSwitchSetType{}
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		vswitches, pagination, err := client.DescribeVSwitches(&args)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, vswitches...)
+		nextPage := pagination.NextPage()
+		if nextPage == nil {
+			break
+		}
+		args.Pagination = *nextPage
+	}
+	return result, nil
+}
+
+// findZone returns the first zone in region available for the resource type
+func (d *Driver) findZone(resourceType ecs.ResourceType) (string, error) {
+	client, err := d.getClient()
+	if err != nil {
+		return "", err
+	}
+	zones, err := client.DescribeZones(d.Region)
+	if err != nil {
+		return "", fmt.Errorf("%s | Failed to describe zones in region %s: %v", d.MachineName, d.Region, err)
+	}
+	for _, zone := range zones {
+		for _, t := range zone.AvailableResourceCreation.ResourceTypes {
+			if t == resourceType {
+				return zone.ZoneId, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s | No zone available for %s in region %s", d.MachineName, resourceType, d.Region)
+}
+
+// nextVSwitchCidrBlock returns the first /24 block following vSwitchCidrBlock
+// within vpcCidrBlock which is not used yet
+func nextVSwitchCidrBlock(used []string) (string, error) {
+	_, vpcNet, err := net.ParseCIDR(vpcCidrBlock)
+	if err != nil {
+		return "", err
+	}
+	ip, _, err := net.ParseCIDR(vSwitchCidrBlock)
+	if err != nil {
+		return "", err
+	}
+	ip = ip.To4()
+
+	for ; vpcNet.Contains(ip); ip[1]++ {
+		candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(24, 32)}
+		inUse := false
+		for _, cidr := range used {
+			_, usedNet, err := net.ParseCIDR(cidr)
+			if err == nil && overlaps(candidate, usedNet) {
+				inUse = true
+				break
+			}
+		}
+		if !inUse {
+			return candidate.String(), nil
+		}
+		if ip[1] == 255 {
+			break
+		}
+	}
+	return "", fmt.Errorf("no free CIDR block in %s", vpcCidrBlock)
+}
+
+// countInstances returns the number of instances matched with args
+func (d *Driver) countInstances(args ecs.DescribeInstancesArgs) (int, error) {
+	args.RegionId = d.Region
+	client, err := d.getClient()
+	if err != nil {
+		return 0, err
+	}
+	_, pagination, err := client.DescribeInstances(&args)
+	if err != nil {
+		return 0, err
+	}
+	return pagination.TotalCount, nil
+}
+
+// waitForInstanceDeleted waits until the deleted instance is gone, the
+// resources used by it can not be released before that
+func (d *Driver) waitForInstanceDeleted(instanceId string) error {
+	err := mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		count, err := d.countInstances(ecs.DescribeInstancesArgs{InstanceIds: fmt.Sprintf("[%q]", instanceId)})
+		return count == 0, err
+	}, maxRetry, 3*time.Second)
+	if err != nil {
+		return fmt.Errorf("%s | Failed to wait instance %s deleted: %v", d.MachineName, instanceId, err)
+	}
+	return nil
+}
+
+// cleanupVPC removes the VSwitch and VPC created by the machine once no
+// instance is using them, the ones found by name may belong to the user
+func (d *Driver) cleanupVPC(instanceId string) error {
+	if !d.VSwitchOwned && !d.VpcOwned {
+		return nil
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	if err := d.waitForInstanceDeleted(instanceId); err != nil {
+		return err
+	}
+
+	if d.VSwitchOwned {
+		count, err := d.countInstances(ecs.DescribeInstancesArgs{VSwitchId: d.VSwitchId})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Infof("%s | VSwitch %s is still used by %d instance(s)", d.MachineName, d.VSwitchId, count)
+			return nil
+		}
+
+		log.Infof("%s | Deleting VSwitch %s ...", d.MachineName, d.VSwitchId)
+		if err := retry(func() error { return client.DeleteVSwitch(d.VSwitchId) }); err != nil {
+			return fmt.Errorf("%s | Failed to delete VSwitch %s: %v", d.MachineName, d.VSwitchId, err)
+		}
+		d.VSwitchOwned = false
+	}
+
+	if !d.VpcOwned {
+		return nil
+	}
+
+	count, err := d.countInstances(ecs.DescribeInstancesArgs{VpcId: d.VpcId})
+	if err != nil {
+		return err
+	}
+	vswitches, err := d.describeVSwitches(d.VpcId)
+	if err != nil {
+		return err
+	}
+	if count > 0 || len(vswitches) > 0 {
+		log.Infof("%s | VPC %s is still in use", d.MachineName, d.VpcId)
+		return nil
+	}
+
+	// The security group must be deleted before the VPC. The group given by
+	// the user is kept, and the one of the machine is handled by
+	// cleanupSecurityGroup already
+	if d.SecurityGroupId != "" && !d.ExistingSecurityGroup && !d.MachineSecurityGroup {
+		if err := retry(d.deleteSecurityGroup); err != nil {
+			return fmt.Errorf("%s | Failed to delete security group %s: %v", d.MachineName, d.SecurityGroupId, err)
+		}
+		d.SecurityGroupId = ""
+	}
+
+	log.Infof("%s | Deleting VPC %s ...", d.MachineName, d.VpcId)
+	if err := retry(func() error { return client.DeleteVpc(d.VpcId) }); err != nil {
+		return fmt.Errorf("%s | Failed to delete VPC %s: %v", d.MachineName, d.VpcId, err)
+	}
+	d.VpcOwned = false
+	return nil
+}
+
+// isTransientError returns true if the operation failed because the resources
+// it depends on are still in use or changing state, e.g. the instance being
+// deleted, or because the API is not reachable for the moment
+func isTransientError(err error) bool {
+	e, ok := err.(*common.Error)
+	if !ok {
+		return false
+	}
+	if e.StatusCode < 0 || e.StatusCode >= 500 {
+		return true
+	}
+	for _, s := range []string{"DependencyViolation", "Status", "InUse", "Conflict", "Processing", "Throttling"} {
+		if strings.Contains(e.Code, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// retry calls f until it succeeds or maxRetry is reached, it is used for
+// the operations which fail while the dependent resources are being released.
+// The other errors, e.g. Forbidden, are returned at once
+func retry(f func() error) error {
+	var err error
+	for count := 0; count <= maxRetry; count++ {
+		if err = f(); err == nil || !isTransientError(err) {
+			return err
+		}
+		log.Debugf("Retrying after error: %v", err)
+		time.Sleep(time.Duration(5000+mrand.Int63n(2000)) * time.Millisecond)
+	}
+	return err
+}
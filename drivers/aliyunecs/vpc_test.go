@@ -0,0 +1,230 @@
+package aliyunecs
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextVSwitchCidrBlock(t *testing.T) {
+	cidr, err := nextVSwitchCidrBlock(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.0/24", cidr)
+
+	cidr, err = nextVSwitchCidrBlock([]string{"10.1.0.0/24", "10.2.0.0/16"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.3.0.0/24", cidr)
+
+	// The blocks containing a smaller VSwitch are in use as well
+	cidr, err = nextVSwitchCidrBlock([]string{"10.1.0.0/16", "10.2.0.128/25"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.3.0.0/24", cidr)
+}
+
+func TestConfigureVPC(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.handle("DescribeVpcs", func(params url.Values) (int, interface{}) {
+		vpcs := ecs.DescribeVpcsResponse{}
+		if params.Get("VpcId") != "" {
+			vpcs.Vpcs.Vpc = []ecs.VpcSetType{{VpcId: "vpc-new", Status: ecs.VpcStatusAvailable}}
+		}
+		return http.StatusOK, vpcs
+	})
+	f.respond("CreateVpc", ecs.CreateVpcResponse{VpcId: "vpc-new", VRouterId: "vrt-new"})
+
+	zones := ecs.DescribeZonesResponse{}
+	zones.Zones.Zone = []ecs.ZoneType{
+		{ZoneId: "cn-hangzhou-a", AvailableResourceCreation: ecs.AvailableResourceCreationType{ResourceTypes: []ecs.ResourceType{ecs.ResourceTypeInstance}}},
+		{ZoneId: "cn-hangzhou-b", AvailableResourceCreation: ecs.AvailableResourceCreationType{ResourceTypes: []ecs.ResourceType{ecs.ResourceTypeVSwitch}}},
+	}
+	f.respond("DescribeZones", zones)
+
+	f.handle("DescribeVSwitches", func(params url.Values) (int, interface{}) {
+		vswitches := ecs.DescribeVSwitchesResponse{}
+		if params.Get("VSwitchId") != "" {
+			vswitches.VSwitches.VSwitch = []ecs.VSwitchSetType{{VSwitchId: "vsw-new", Status: ecs.VSwitchStatusAvailable}}
+		}
+		return http.StatusOK, vswitches
+	})
+	f.handle("CreateVSwitch", func(params url.Values) (int, interface{}) {
+		assert.Equal(t, "cn-hangzhou-b", params.Get("ZoneId"))
+		assert.Equal(t, vSwitchCidrBlock, params.Get("CidrBlock"))
+		return http.StatusOK, ecs.CreateVSwitchResponse{VSwitchId: "vsw-new"}
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+
+	assert.NoError(t, d.configureVPC())
+	assert.Equal(t, "vpc-new", d.VpcId)
+	assert.Equal(t, "vsw-new", d.VSwitchId)
+	assert.Equal(t, "cn-hangzhou-b", d.Zone)
+	assert.True(t, d.VpcOwned)
+	assert.True(t, d.VSwitchOwned)
+}
+
+func TestConfigureExistingVPC(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	vpcs := ecs.DescribeVpcsResponse{}
+	vpcs.Vpcs.Vpc = []ecs.VpcSetType{{VpcId: "vpc-user", VpcName: machineVpcName}}
+	f.respond("DescribeVpcs", vpcs)
+	vswitches := ecs.DescribeVSwitchesResponse{}
+	vswitches.VSwitches.VSwitch = []ecs.VSwitchSetType{{VSwitchId: "vsw-user", VSwitchName: machineVpcName, ZoneId: "cn-hangzhou-a"}}
+	f.respond("DescribeVSwitches", vswitches)
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.Zone = "cn-hangzhou-a"
+
+	assert.NoError(t, d.configureVPC())
+	assert.Equal(t, "vpc-user", d.VpcId)
+	assert.Equal(t, "vsw-user", d.VSwitchId)
+	assert.False(t, d.VpcOwned)
+	assert.False(t, d.VSwitchOwned)
+	assert.False(t, f.hasCalled("CreateVpc"))
+	assert.False(t, f.hasCalled("CreateVSwitch"))
+}
+
+func TestCleanupVPC(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.CreateVPC = true
+	d.VpcId = "vpc-new"
+	d.VSwitchId = "vsw-new"
+	d.VpcOwned = true
+	d.VSwitchOwned = true
+	d.SecurityGroupId = "sg-new"
+
+	assert.NoError(t, d.cleanupVPC("i-test"))
+	assert.Equal(t, []string{
+		"DescribeInstances",
+		"DescribeInstances",
+		"DeleteVSwitch",
+		"DescribeInstances",
+		"DescribeVSwitches",
+		"DeleteSecurityGroup",
+		"DeleteVpc",
+	}, f.called())
+	assert.Empty(t, d.SecurityGroupId)
+	assert.False(t, d.VpcOwned)
+	assert.False(t, d.VSwitchOwned)
+}
+
+func TestCleanupVPCNotOwned(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.CreateVPC = true
+	d.VpcId = "vpc-user"
+	d.VSwitchId = "vsw-user"
+	d.SecurityGroupId = "sg-new"
+
+	assert.NoError(t, d.cleanupVPC("i-test"))
+	assert.Empty(t, f.called())
+}
+
+func TestCleanupVPCOwnedVSwitch(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.CreateVPC = true
+	d.VpcId = "vpc-user"
+	d.VSwitchId = "vsw-new"
+	d.VSwitchOwned = true
+
+	assert.NoError(t, d.cleanupVPC("i-test"))
+	assert.True(t, f.hasCalled("DeleteVSwitch"))
+	assert.False(t, f.hasCalled("DeleteVpc"))
+}
+
+func TestCleanupVPCKeepsSecurityGroups(t *testing.T) {
+	for _, existing := range []bool{true, false} {
+		f := newFakeECS()
+
+		d, err := getFakeECSDriver(f)
+		assert.NoError(t, err)
+		d.CreateVPC = true
+		d.VpcId = "vpc-new"
+		d.VSwitchId = "vsw-new"
+		d.VpcOwned = true
+		d.VSwitchOwned = true
+		d.SecurityGroupId = "sg-test"
+		// The group given by the user, or the group of the machine which
+		// cleanupSecurityGroup failed to delete
+		d.ExistingSecurityGroup = existing
+		d.MachineSecurityGroup = !existing
+
+		assert.NoError(t, d.cleanupVPC("i-test"))
+		assert.False(t, f.hasCalled("DeleteSecurityGroup"))
+		assert.True(t, f.hasCalled("DeleteVpc"))
+		f.Close()
+	}
+}
+
+func TestCleanupVPCInUse(t *testing.T) {
+	f := newFakeECS()
+	defer f.Close()
+
+	f.handle("DescribeInstances", func(params url.Values) (int, interface{}) {
+		instances := ecs.DescribeInstancesResponse{}
+		if params.Get("VSwitchId") != "" {
+			instances.TotalCount = 1
+		}
+		return http.StatusOK, instances
+	})
+
+	d, err := getFakeECSDriver(f)
+	assert.NoError(t, err)
+	d.CreateVPC = true
+	d.VpcId = "vpc-new"
+	d.VSwitchId = "vsw-new"
+	d.VpcOwned = true
+	d.VSwitchOwned = true
+
+	assert.NoError(t, d.cleanupVPC("i-test"))
+	assert.False(t, f.hasCalled("DeleteVSwitch"))
+	assert.False(t, f.hasCalled("DeleteVpc"))
+}
+
+func TestIsTransientError(t *testing.T) {
+	for code, transient := range map[string]bool{
+		"DependencyViolation.Instance": true,
+		"IncorrectVSwitchStatus":       true,
+		"OperationConflict":            true,
+		"Forbidden":                    false,
+		"InvalidVpcId.NotFound":        false,
+	} {
+		err := &common.Error{ErrorResponse: common.ErrorResponse{Code: code}, StatusCode: 400}
+		assert.Equal(t, transient, isTransientError(err), code)
+	}
+
+	assert.True(t, isTransientError(common.GetClientErrorFromString("connection refused")))
+	assert.False(t, isTransientError(errors.New("no credentials")))
+}
+
+func TestRetryPermanentError(t *testing.T) {
+	calls := 0
+	err := retry(func() error {
+		calls++
+		return &common.Error{ErrorResponse: common.ErrorResponse{Code: "Forbidden"}, StatusCode: 403}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
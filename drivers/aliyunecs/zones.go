@@ -0,0 +1,77 @@
+package aliyunecs
+
+import (
+	"fmt"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+)
+
+const instanceTypesCacheFile = "instance-types.json"
+
+type zoneAttributes struct {
+	ecs.ZoneType
+	AvailableInstanceTypes struct {
+		InstanceTypes []string
+	}
+}
+
+type describeZonesResponse struct {
+	common.Response
+	Zones struct {
+		Zone []zoneAttributes
+	}
+}
+
+// describeZones describes the zones with their available instance types, the
+// cache of zones is refreshed with them
+func (d *Driver) describeZones() ([]zoneAttributes, error) {
+	args := ecs.DescribeZonesArgs{RegionId: d.Region}
+	response := describeZonesResponse{}
+	err := d.invoke("DescribeZones", &args, &response)
+	if err != nil {
+		return nil, err
+	}
+	d.writeCache(d.zonesCacheFile(), response.Zones.Zone)
+	return response.Zones.Zone, nil
+}
+
+func (d *Driver) zonesCacheFile() string {
+	return fmt.Sprintf("zones-%s.json", d.Region)
+}
+
+// getZones returns the zones of region from the cache in the store directory,
+// the zones are described if the cache is missing or expired
+func (d *Driver) getZones() ([]zoneAttributes, error) {
+	zones := []zoneAttributes{}
+	if d.readCache(d.zonesCacheFile(), &zones) {
+		return zones, nil
+	}
+
+	zones, err := d.describeZones()
+	if err != nil {
+		return nil, fmt.Errorf("%s | Failed to describe zones in region %s: %v", d.MachineName, d.Region, err)
+	}
+	return zones, nil
+}
+
+// getInstanceTypes returns the instance types from the cache in the store
+// directory, the instance types are described if the cache is missing or
+// expired
+func (d *Driver) getInstanceTypes() ([]ecs.InstanceTypeItemType, error) {
+	instanceTypes := []ecs.InstanceTypeItemType{}
+	if d.readCache(instanceTypesCacheFile, &instanceTypes) {
+		return instanceTypes, nil
+	}
+
+	client, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+	instanceTypes, err = client.DescribeInstanceTypes()
+	if err != nil {
+		return nil, fmt.Errorf("%s | Failed to describe instance types: %v", d.MachineName, err)
+	}
+	d.writeCache(instanceTypesCacheFile, instanceTypes)
+	return instanceTypes, nil
+}
@@ -3,17 +3,21 @@ package check
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
 )
 
 var (
-	DefaultConnChecker ConnChecker
-	ErrSwarmNotStarted = errors.New("Connection to Swarm cannot be checked but the certs are valid. Maybe swarm is not started")
+	DefaultConnChecker    ConnChecker
+	ErrSwarmNotStarted    = errors.New("Connection to Swarm cannot be checked but the certs are valid. Maybe swarm is not started")
+	ErrSwarmBehindBastion = errors.New("The Swarm master behind an SSH bastion can not be reached through a tunnel")
 )
 
 func init() {
@@ -33,6 +37,23 @@ Be advised that this will trigger a Docker daemon restart which will stop runnin
 `, e.hostURL, e.wrappedErr)
 }
 
+// ErrTunnelNotRunning for when the machine is behind an SSH bastion and no
+// tunnel forwards its Docker daemon to localhost.
+type ErrTunnelNotRunning struct {
+	hostName string
+	port     int
+}
+
+func (e ErrTunnelNotRunning) Error() string {
+	listening := "No tunnel has been opened"
+	if e.port != 0 {
+		listening = fmt.Sprintf("No tunnel is listening on localhost:%d", e.port)
+	}
+	return fmt.Sprintf(`The Docker daemon of host %q is behind an SSH bastion. %s.
+You can open it using 'docker-machine tunnel %s' in another shell, and keep it running while using the Docker client.
+`, e.hostName, listening, e.hostName)
+}
+
 type ConnChecker interface {
 	Check(*host.Host, bool) (dockerHost string, authOptions *auth.Options, err error)
 }
@@ -60,7 +81,25 @@ func (mcc *MachineConnChecker) Check(h *host.Host, swarm bool) (string, *auth.Op
 
 	authOptions := h.AuthOptions()
 
-	if err := checkCert(u.Host, authOptions); err != nil {
+	// The daemon behind the bastion is reached through the tunnel opened by
+	// `docker-machine tunnel`, its certificate is valid for localhost as well
+	bastion, err := drivers.GetSSHBastion(h.Driver)
+	if err != nil {
+		return "", &auth.Options{}, err
+	}
+	certAddr := u.Host
+	if bastion != nil {
+		if swarm {
+			return "", &auth.Options{}, ErrSwarmBehindBastion
+		}
+		certAddr = fmt.Sprintf("localhost:%d", h.TunnelPort)
+		if h.TunnelPort == 0 || !isListening(certAddr) {
+			return "", &auth.Options{}, ErrTunnelNotRunning{hostName: h.Name, port: h.TunnelPort}
+		}
+		dockerURL = "tcp://" + certAddr
+	}
+
+	if err := checkCert(certAddr, authOptions); err != nil {
 		if swarm {
 			// Connection to the swarm port cannot be checked. Maybe it's just the swarm containers that are down
 			// TODO: check the containers and restart them
@@ -76,6 +115,15 @@ func (mcc *MachineConnChecker) Check(h *host.Host, swarm bool) (string, *auth.Op
 	return dockerURL, authOptions, nil
 }
 
+func isListening(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
 func checkCert(hostURL string, authOptions *auth.Options) error {
 	valid, err := cert.ValidateCertificate(hostURL, authOptions)
 	if !valid || err != nil {
@@ -2,12 +2,18 @@ package check
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"testing"
 
 	"crypto/tls"
 
+	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/swarm"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,3 +64,50 @@ func TestCheckCert(t *testing.T) {
 		assert.Equal(t, c.expectedErr, err)
 	}
 }
+
+type fakeBastionDriver struct {
+	*fakedriver.Driver
+}
+
+func (d *fakeBastionDriver) GetSSHBastion() (string, error) {
+	return "admin@jump.example.com", nil
+}
+
+func TestCheckBehindBastion(t *testing.T) {
+	fcg := FakeCertGenerator{fakeValidateCertificate: &FakeValidateCertificate{true, nil}}
+	cert.SetCertGenerator(fcg)
+
+	h := &host.Host{
+		Name: "private",
+		Driver: &fakeBastionDriver{
+			Driver: &fakedriver.Driver{
+				MockState: state.Running,
+				MockIP:    "10.0.0.2",
+			},
+		},
+		HostOptions: &host.Options{
+			AuthOptions:  &auth.Options{},
+			SwarmOptions: &swarm.Options{Master: true, Host: "tcp://0.0.0.0:3376"},
+		},
+	}
+	checker := &MachineConnChecker{}
+
+	// No tunnel has been opened yet
+	_, _, err := checker.Check(h, false)
+	assert.Equal(t, ErrTunnelNotRunning{hostName: "private"}, err)
+
+	_, _, err = checker.Check(h, true)
+	assert.Equal(t, ErrSwarmBehindBastion, err)
+
+	tunnel, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	h.TunnelPort = tunnel.Addr().(*net.TCPAddr).Port
+
+	dockerHost, _, err := checker.Check(h, false)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("tcp://localhost:%d", h.TunnelPort), dockerHost)
+
+	tunnel.Close()
+	_, _, err = checker.Check(h, false)
+	assert.Equal(t, ErrTunnelNotRunning{hostName: "private", port: h.TunnelPort}, err)
+}
@@ -0,0 +1,27 @@
+package drivers
+
+import "github.com/docker/machine/libmachine/ssh"
+
+// BastionProvider is implemented by drivers whose machines may only be
+// reachable through an SSH bastion, e.g. the machines without public IP.
+type BastionProvider interface {
+	// GetSSHBastion returns the bastion in the form user@host[:port], or an
+	// empty string if the machine is reached directly
+	GetSSHBastion() (string, error)
+}
+
+// GetSSHBastion returns the bastion to reach the machine through, or nil if
+// the machine is reached directly
+func GetSSHBastion(d Driver) (*ssh.Bastion, error) {
+	provider, ok := d.(BastionProvider)
+	if !ok {
+		return nil, nil
+	}
+
+	spec, err := provider.GetSSHBastion()
+	if err != nil || spec == "" {
+		return nil, err
+	}
+
+	return ssh.ParseBastion(spec)
+}
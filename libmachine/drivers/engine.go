@@ -0,0 +1,49 @@
+package drivers
+
+import "strings"
+
+// EngineConfigurer is implemented by drivers which need the Docker daemon to
+// be configured to match the machine, e.g. the bridge subnet routed to it.
+type EngineConfigurer interface {
+	// GetEngineFlags returns the daemon flags in the form of --engine-opt,
+	// e.g. bip=172.16.1.1/24
+	GetEngineFlags() ([]string, error)
+}
+
+// GetEngineFlags returns the daemon flags required by the driver, or nil if
+// the driver has none
+func GetEngineFlags(d Driver) ([]string, error) {
+	configurer, ok := d.(EngineConfigurer)
+	if !ok {
+		return nil, nil
+	}
+
+	return configurer.GetEngineFlags()
+}
+
+// MergeEngineFlags appends the flags required by the driver to the flags
+// given by the user, the flags given by the user take precedence
+func MergeEngineFlags(userFlags, driverFlags []string) []string {
+	merged := append([]string{}, userFlags...)
+
+	given := map[string]bool{}
+	for _, f := range userFlags {
+		given[engineFlagName(f)] = true
+	}
+	for _, f := range driverFlags {
+		if !given[engineFlagName(f)] {
+			merged = append(merged, f)
+		}
+	}
+
+	return merged
+}
+
+// engineFlagName returns the name of flag in the form name=value or name value
+func engineFlagName(flag string) string {
+	name := strings.TrimLeft(flag, "-")
+	if i := strings.IndexAny(name, "= "); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,17 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeEngineFlags(t *testing.T) {
+	merged := MergeEngineFlags(
+		[]string{"bip=10.0.0.1/24", "mtu 1450"},
+		[]string{"bip=172.16.1.1/24", "fixed-cidr=172.16.1.0/24", "mtu=1500"},
+	)
+
+	assert.Equal(t, []string{"bip=10.0.0.1/24", "mtu 1450", "fixed-cidr=172.16.1.0/24"}, merged)
+	assert.Empty(t, MergeEngineFlags(nil, nil))
+}
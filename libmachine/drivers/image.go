@@ -0,0 +1,28 @@
+package drivers
+
+// ImageCreator is implemented by drivers which are able to create a custom
+// image from a machine, so that the machines created from the image have
+// Docker installed already.
+type ImageCreator interface {
+	// CreateImage creates the image with the name and returns its ID, the
+	// machine is stopped to create the image and started again if it was
+	// running
+	CreateImage(name string) (string, error)
+}
+
+// EngineImageUser is implemented by drivers which are able to create the
+// machine from the image of a provisioned machine, e.g. the image created by
+// ImageCreator.
+type EngineImageUser interface {
+	// EnginePreinstalled returns true if Docker is installed on the image
+	// of the machine
+	EnginePreinstalled() bool
+}
+
+// EnginePreinstalled returns true if the driver reports that Docker is
+// installed on the image of the machine, so the provisioner can skip the
+// installation
+func EnginePreinstalled(d Driver) bool {
+	user, ok := d.(EngineImageUser)
+	return ok && user.EnginePreinstalled()
+}
@@ -0,0 +1,17 @@
+package drivers
+
+// InfoTable is a listing of the resources offered by the provider, e.g. the
+// regions or the instance types.
+type InfoTable struct {
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// InfoReporter is implemented by drivers which are able to list the choices
+// of their create flags from the provider, without any machine created. The
+// driver is configured with the defaults and environment variables of the
+// create flags before GetDriverInfo is called.
+type InfoReporter interface {
+	GetDriverInfo() ([]InfoTable, error)
+}
@@ -0,0 +1,20 @@
+package drivers
+
+// LabelOptions describes the changes to the labels of a machine.
+type LabelOptions struct {
+	// Set adds the labels, or updates the values of existing ones
+	Set map[string]string
+
+	// Remove is the keys of the labels to remove
+	Remove []string
+}
+
+// Labeler is implemented by drivers which keep labels on the resources of a
+// machine at the provider, e.g. the tags of a cloud instance.
+type Labeler interface {
+	// GetLabels returns the labels of the machine
+	GetLabels() (map[string]string, error)
+
+	// SetLabels changes the labels of the machine
+	SetLabels(opts LabelOptions) error
+}
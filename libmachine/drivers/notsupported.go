@@ -87,3 +87,14 @@ func (d *DriverNotSupported) Kill() error {
 func (d *DriverNotSupported) Upgrade() error {
 	return NotSupported{d.DriverName()}
 }
+
+// NotImplemented is returned when a driver does not implement an optional
+// operation, e.g. taking a snapshot of a machine.
+type NotImplemented struct {
+	DriverName string
+	Operation  string
+}
+
+func (e NotImplemented) Error() string {
+	return fmt.Sprintf("Driver %q does not support the %s operation.", e.DriverName, e.Operation)
+}
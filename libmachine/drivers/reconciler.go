@@ -0,0 +1,9 @@
+package drivers
+
+// Reconciler is implemented by drivers which are able to bring the cloud
+// resources of an existing machine (e.g. firewall rules) back in line with
+// the configuration the machine was created with.
+type Reconciler interface {
+	// Reconcile is called before the machine is re-provisioned
+	Reconcile() error
+}
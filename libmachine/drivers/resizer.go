@@ -0,0 +1,18 @@
+package drivers
+
+// ResizeOptions describes the new size of a machine, the zero values leave
+// the corresponding settings unchanged.
+type ResizeOptions struct {
+	// Size is the driver specific size of the machine, e.g. instance type
+	Size string
+
+	// Bandwidth is the maximum outbound bandwidth in Mbps
+	Bandwidth int
+}
+
+// Resizer is implemented by drivers which are able to change the size of an
+// existing machine in place.
+type Resizer interface {
+	// Resize changes the size of the machine, which may be restarted
+	Resize(opts ResizeOptions) error
+}
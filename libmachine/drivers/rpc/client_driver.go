@@ -3,6 +3,7 @@ package rpcdriver
 import (
 	"fmt"
 	"net/rpc"
+	"strings"
 	"sync"
 	"time"
 
@@ -83,6 +84,19 @@ const (
 	RestartMethod            = `.Restart`
 	KillMethod               = `.Kill`
 	UpgradeMethod            = `.Upgrade`
+	CreateSnapshotMethod     = `.CreateSnapshot`
+	ListSnapshotsMethod      = `.ListSnapshots`
+	RemoveSnapshotMethod     = `.RemoveSnapshot`
+	ReconcileMethod          = `.Reconcile`
+	ResizeMethod             = `.Resize`
+	GetLabelsMethod          = `.GetLabels`
+	SetLabelsMethod          = `.SetLabels`
+	GetStatsMethod           = `.GetStats`
+	GetSSHBastionMethod      = `.GetSSHBastion`
+	GetDriverInfoMethod      = `.GetDriverInfo`
+	CreateImageMethod        = `.CreateImage`
+	GetEngineFlagsMethod     = `.GetEngineFlags`
+	EnginePreinstalledMethod = `.EnginePreinstalled`
 )
 
 func (ic *InternalClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
@@ -92,6 +106,13 @@ func (ic *InternalClient) Call(serviceMethod string, args interface{}, reply int
 	return ic.RPCClient.Call(ic.rpcServiceName+serviceMethod, args, reply)
 }
 
+// isMethodNotFound tells if the call failed because the plugin was built
+// before the method was added to the RPC server
+func isMethodNotFound(err error) bool {
+	serverErr, ok := err.(rpc.ServerError)
+	return ok && strings.HasPrefix(string(serverErr), "rpc: can't find method")
+}
+
 func (ic *InternalClient) switchToV0() {
 	ic.rpcServiceName = RPCServiceNameV0
 }
@@ -361,3 +382,123 @@ func (c *RPCClientDriver) Kill() error {
 func (c *RPCClientDriver) Upgrade() error {
 	return c.Client.Call(UpgradeMethod, struct{}{}, nil)
 }
+
+func (c *RPCClientDriver) CreateSnapshot(name string) (string, error) {
+	var id string
+
+	if err := c.Client.Call(CreateSnapshotMethod, name, &id); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (c *RPCClientDriver) ListSnapshots() ([]drivers.Snapshot, error) {
+	var snapshots []drivers.Snapshot
+
+	if err := c.Client.Call(ListSnapshotsMethod, struct{}{}, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (c *RPCClientDriver) RemoveSnapshot(id string) error {
+	return c.Client.Call(RemoveSnapshotMethod, id, nil)
+}
+
+// Reconcile is a no-op for the plugins which don't know about reconciliation
+func (c *RPCClientDriver) Reconcile() error {
+	if err := c.Client.Call(ReconcileMethod, struct{}{}, nil); err != nil && !isMethodNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *RPCClientDriver) Resize(opts drivers.ResizeOptions) error {
+	return c.Client.Call(ResizeMethod, opts, nil)
+}
+
+func (c *RPCClientDriver) GetLabels() (map[string]string, error) {
+	var labels map[string]string
+
+	if err := c.Client.Call(GetLabelsMethod, struct{}{}, &labels); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+func (c *RPCClientDriver) SetLabels(opts drivers.LabelOptions) error {
+	return c.Client.Call(SetLabelsMethod, opts, nil)
+}
+
+func (c *RPCClientDriver) GetStats(opts drivers.StatsOptions) ([]drivers.StatsSample, error) {
+	var samples []drivers.StatsSample
+
+	if err := c.Client.Call(GetStatsMethod, opts, &samples); err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// GetSSHBastion returns no bastion for the plugins which don't know about
+// bastions, their machines being reached directly
+func (c *RPCClientDriver) GetSSHBastion() (string, error) {
+	bastion, err := c.rpcStringCall(GetSSHBastionMethod)
+	if isMethodNotFound(err) {
+		return "", nil
+	}
+
+	return bastion, err
+}
+
+func (c *RPCClientDriver) GetDriverInfo() ([]drivers.InfoTable, error) {
+	var tables []drivers.InfoTable
+
+	if err := c.Client.Call(GetDriverInfoMethod, struct{}{}, &tables); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+func (c *RPCClientDriver) CreateImage(name string) (string, error) {
+	var id string
+
+	if err := c.Client.Call(CreateImageMethod, name, &id); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// GetEngineFlags returns no flags for the plugins which don't know about
+// engine flags
+func (c *RPCClientDriver) GetEngineFlags() ([]string, error) {
+	var flags []string
+
+	if err := c.Client.Call(GetEngineFlagsMethod, struct{}{}, &flags); err != nil {
+		if isMethodNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return flags, nil
+}
+
+// EnginePreinstalled returns false if the plugin fails to tell, so Docker is
+// installed as usual
+func (c *RPCClientDriver) EnginePreinstalled() bool {
+	var preinstalled bool
+
+	if err := c.Client.Call(EnginePreinstalledMethod, struct{}{}, &preinstalled); err != nil {
+		log.Debugf("Unable to tell if Docker is installed on the image: %s", err)
+		return false
+	}
+
+	return preinstalled
+}
@@ -0,0 +1,71 @@
+package rpcdriver
+
+import (
+	"net"
+	"net/rpc"
+	"testing"
+
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+)
+
+// legacyServerDriver stands for a plugin built before the optional driver
+// methods were added to the RPC server
+type legacyServerDriver struct{}
+
+func (s *legacyServerDriver) GetVersion(_ *struct{}, reply *int) error {
+	*reply = version.APIVersion
+	return nil
+}
+
+func newLegacyClientDriver(t *testing.T) *RPCClientDriver {
+	server := rpc.NewServer()
+	if err := server.RegisterName(RPCServiceNameV1, &legacyServerDriver{}); err != nil {
+		t.Fatal(err)
+	}
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	return &RPCClientDriver{
+		Client: NewInternalClient(rpc.NewClient(clientConn)),
+	}
+}
+
+func TestIsMethodNotFound(t *testing.T) {
+	driver := newLegacyClientDriver(t)
+	defer driver.Client.RPCClient.Close()
+
+	err := driver.Client.Call(ReconcileMethod, struct{}{}, nil)
+
+	assert.True(t, isMethodNotFound(err))
+	assert.False(t, isMethodNotFound(nil))
+	assert.False(t, isMethodNotFound(rpc.ServerError("API not available")))
+}
+
+func TestReconcileLegacyPlugin(t *testing.T) {
+	driver := newLegacyClientDriver(t)
+	defer driver.Client.RPCClient.Close()
+
+	assert.NoError(t, driver.Reconcile())
+}
+
+func TestGetSSHBastionLegacyPlugin(t *testing.T) {
+	driver := newLegacyClientDriver(t)
+	defer driver.Client.RPCClient.Close()
+
+	bastion, err := driver.GetSSHBastion()
+
+	assert.NoError(t, err)
+	assert.Empty(t, bastion)
+}
+
+func TestGetEngineFlagsLegacyPlugin(t *testing.T) {
+	driver := newLegacyClientDriver(t)
+	defer driver.Client.RPCClient.Close()
+
+	flags, err := driver.GetEngineFlags()
+
+	assert.NoError(t, err)
+	assert.Nil(t, flags)
+}
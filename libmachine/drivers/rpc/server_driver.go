@@ -225,3 +225,160 @@ func (r *RPCServerDriver) Heartbeat(_ *struct{}, _ *struct{}) error {
 	r.HeartbeatCh <- true
 	return nil
 }
+
+func (r *RPCServerDriver) snapshotter() (drivers.Snapshotter, error) {
+	s, ok := r.ActualDriver.(drivers.Snapshotter)
+	if !ok {
+		return nil, drivers.NotImplemented{
+			DriverName: r.ActualDriver.DriverName(),
+			Operation:  "snapshot",
+		}
+	}
+	return s, nil
+}
+
+func (r *RPCServerDriver) CreateSnapshot(name string, reply *string) error {
+	s, err := r.snapshotter()
+	if err != nil {
+		return err
+	}
+
+	id, err := s.CreateSnapshot(name)
+	*reply = id
+	return err
+}
+
+func (r *RPCServerDriver) ListSnapshots(_ *struct{}, reply *[]drivers.Snapshot) error {
+	s, err := r.snapshotter()
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := s.ListSnapshots()
+	*reply = snapshots
+	return err
+}
+
+func (r *RPCServerDriver) RemoveSnapshot(id string, _ *struct{}) error {
+	s, err := r.snapshotter()
+	if err != nil {
+		return err
+	}
+
+	return s.RemoveSnapshot(id)
+}
+
+// Reconcile is a no-op for the drivers which have nothing to reconcile
+func (r *RPCServerDriver) Reconcile(_ *struct{}, _ *struct{}) error {
+	if reconciler, ok := r.ActualDriver.(drivers.Reconciler); ok {
+		return reconciler.Reconcile()
+	}
+	return nil
+}
+
+func (r *RPCServerDriver) Resize(opts drivers.ResizeOptions, _ *struct{}) error {
+	resizer, ok := r.ActualDriver.(drivers.Resizer)
+	if !ok {
+		return drivers.NotImplemented{
+			DriverName: r.ActualDriver.DriverName(),
+			Operation:  "resize",
+		}
+	}
+
+	return resizer.Resize(opts)
+}
+
+// GetLabels returns no labels for the drivers which don't keep labels, so that
+// the machines can be filtered by labels regardless of their drivers
+func (r *RPCServerDriver) GetLabels(_ *struct{}, reply *map[string]string) error {
+	labeler, ok := r.ActualDriver.(drivers.Labeler)
+	if !ok {
+		*reply = map[string]string{}
+		return nil
+	}
+
+	labels, err := labeler.GetLabels()
+	*reply = labels
+	return err
+}
+
+func (r *RPCServerDriver) SetLabels(opts drivers.LabelOptions, _ *struct{}) error {
+	labeler, ok := r.ActualDriver.(drivers.Labeler)
+	if !ok {
+		return drivers.NotImplemented{
+			DriverName: r.ActualDriver.DriverName(),
+			Operation:  "label",
+		}
+	}
+
+	return labeler.SetLabels(opts)
+}
+
+func (r *RPCServerDriver) GetStats(opts drivers.StatsOptions, reply *[]drivers.StatsSample) error {
+	reporter, ok := r.ActualDriver.(drivers.StatsReporter)
+	if !ok {
+		return drivers.NotImplemented{
+			DriverName: r.ActualDriver.DriverName(),
+			Operation:  "stats",
+		}
+	}
+
+	samples, err := reporter.GetStats(opts)
+	*reply = samples
+	return err
+}
+
+// GetSSHBastion returns no bastion for the drivers whose machines are always
+// reached directly
+func (r *RPCServerDriver) GetSSHBastion(_ *struct{}, reply *string) error {
+	provider, ok := r.ActualDriver.(drivers.BastionProvider)
+	if !ok {
+		*reply = ""
+		return nil
+	}
+
+	bastion, err := provider.GetSSHBastion()
+	*reply = bastion
+	return err
+}
+
+func (r *RPCServerDriver) GetDriverInfo(_ *struct{}, reply *[]drivers.InfoTable) error {
+	reporter, ok := r.ActualDriver.(drivers.InfoReporter)
+	if !ok {
+		return drivers.NotImplemented{
+			DriverName: r.ActualDriver.DriverName(),
+			Operation:  "driver info",
+		}
+	}
+
+	tables, err := reporter.GetDriverInfo()
+	*reply = tables
+	return err
+}
+
+func (r *RPCServerDriver) CreateImage(name string, reply *string) error {
+	creator, ok := r.ActualDriver.(drivers.ImageCreator)
+	if !ok {
+		return drivers.NotImplemented{
+			DriverName: r.ActualDriver.DriverName(),
+			Operation:  "image",
+		}
+	}
+
+	id, err := creator.CreateImage(name)
+	*reply = id
+	return err
+}
+
+// GetEngineFlags returns no flags for the drivers which leave the Docker
+// daemon configured as given by the user
+func (r *RPCServerDriver) GetEngineFlags(_ *struct{}, reply *[]string) error {
+	flags, err := drivers.GetEngineFlags(r.ActualDriver)
+	*reply = flags
+	return err
+}
+
+func (r *RPCServerDriver) EnginePreinstalled(_ *struct{}, reply *bool) error {
+	*reply = drivers.EnginePreinstalled(r.ActualDriver)
+	return nil
+}
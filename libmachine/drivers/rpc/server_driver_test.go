@@ -73,3 +73,11 @@ func TestRPCServerDriverCreate(t *testing.T) {
 		assert.Equal(t, tc.expectedErr, tc.serverDriver.Create(nil, nil))
 	}
 }
+
+func TestRPCServerDriverReconcileNotImplemented(t *testing.T) {
+	serverDriver := &RPCServerDriver{
+		ActualDriver: &fakedriver.Driver{},
+	}
+
+	assert.NoError(t, serverDriver.Reconcile(nil, nil))
+}
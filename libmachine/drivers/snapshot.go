@@ -0,0 +1,23 @@
+package drivers
+
+// Snapshot describes a point-in-time copy of the data volume of a machine.
+type Snapshot struct {
+	ID       string
+	Name     string
+	Progress string
+	Size     int
+	Created  string
+}
+
+// Snapshotter is implemented by drivers which are able to snapshot the
+// persistent data volume of a machine.
+type Snapshotter interface {
+	// CreateSnapshot takes a snapshot with the given name and returns its id
+	CreateSnapshot(name string) (string, error)
+
+	// ListSnapshots returns the snapshots taken for the machine
+	ListSnapshots() ([]Snapshot, error)
+
+	// RemoveSnapshot deletes the snapshot with the given id
+	RemoveSnapshot(id string) error
+}
@@ -0,0 +1,37 @@
+package drivers
+
+import "time"
+
+// StatsOptions describes the time range of the metrics of a machine.
+type StatsOptions struct {
+	// Start and End bound the time range of the samples
+	Start time.Time
+	End   time.Time
+
+	// Period is the interval between samples in seconds, the zero value
+	// means the driver default
+	Period int
+}
+
+// StatsSample is the metrics of a machine averaged over a sampling period.
+type StatsSample struct {
+	Time time.Time
+
+	// CPU is the CPU usage in percent
+	CPU float64
+
+	// NetworkIn and NetworkOut are the network throughput in bytes per second
+	NetworkIn  float64
+	NetworkOut float64
+
+	// DiskRead and DiskWrite are the disk throughput in bytes per second
+	DiskRead  float64
+	DiskWrite float64
+}
+
+// StatsReporter is implemented by drivers which are able to report the
+// resource usage of a machine from the monitoring service of the provider.
+type StatsReporter interface {
+	// GetStats returns the samples in the time range in chronological order
+	GetStats(opts StatsOptions) ([]StatsSample, error)
+}
@@ -28,7 +28,12 @@ func GetSSHClientFromDriver(d Driver) (ssh.Client, error) {
 		}
 	}
 
-	client, err := ssh.NewClient(d.GetSSHUsername(), address, port, auth)
+	bastion, err := GetSSHBastion(d)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ssh.NewBastionClient(d.GetSSHUsername(), address, port, auth, bastion)
 	return client, err
 
 }
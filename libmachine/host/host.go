@@ -43,6 +43,7 @@ type Host struct {
 	DriverName    string
 	HostOptions   *Options
 	Name          string
+	TunnelPort    int    `json:",omitempty"`
 	RawDriver     []byte `json:"-"`
 }
 
@@ -89,7 +90,34 @@ func (creator *StandardSSHClientCreator) CreateSSHClient(d drivers.Driver) (ssh.
 		auth.Keys = []string{d.GetSSHKeyPath()}
 	}
 
-	return ssh.NewClient(d.GetSSHUsername(), addr, port, auth)
+	bastion, err := drivers.GetSSHBastion(d)
+	if err != nil {
+		return ssh.ExternalClient{}, err
+	}
+
+	return ssh.NewBastionClient(d.GetSSHUsername(), addr, port, auth, bastion)
+}
+
+// BastionTunnelPort is the first local port allocated to the tunnels to the
+// Docker daemons of the machines behind an SSH bastion. The port allocated to
+// a machine is kept in TunnelPort, and the Docker client is pointed to it.
+const BastionTunnelPort = 12376
+
+// NewBastionTunnel forwards the connections to localAddr to remoteAddr, e.g.
+// the Docker daemon, through the SSH bastion of the machine. It returns nil if
+// the machine has no bastion and is reached directly.
+func (h *Host) NewBastionTunnel(localAddr, remoteAddr string) (*ssh.Tunnel, error) {
+	bastion, err := drivers.GetSSHBastion(h.Driver)
+	if err != nil || bastion == nil {
+		return nil, err
+	}
+
+	auth := &ssh.Auth{}
+	if h.Driver.GetSSHKeyPath() != "" {
+		auth.Keys = []string{h.Driver.GetSSHKeyPath()}
+	}
+
+	return ssh.NewTunnel(bastion, auth, localAddr, remoteAddr)
 }
 
 func (h *Host) runActionForState(action func() error, desiredState state.State) error {
@@ -212,6 +240,12 @@ func (h *Host) ConfigureAuth() error {
 }
 
 func (h *Host) Provision() error {
+	if reconciler, ok := h.Driver.(drivers.Reconciler); ok {
+		if err := reconciler.Reconcile(); err != nil {
+			return fmt.Errorf("Error reconciling machine resources: %s", err)
+		}
+	}
+
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
 		return err
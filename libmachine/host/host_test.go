@@ -5,8 +5,11 @@ import (
 
 	"github.com/docker/machine/drivers/fakedriver"
 	_ "github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/provision"
 	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/swarm"
 )
 
 func TestValidateHostnameValid(t *testing.T) {
@@ -82,3 +85,40 @@ func TestStart(t *testing.T) {
 		t.Fatalf("Expected no error but got one: %s", err)
 	}
 }
+
+type reconcilingDriver struct {
+	*fakedriver.Driver
+	reconciled bool
+}
+
+func (d *reconcilingDriver) Reconcile() error {
+	d.reconciled = true
+	return nil
+}
+
+func TestProvisionReconciles(t *testing.T) {
+	provision.SetDetector(&provision.FakeDetector{
+		Provisioner: NewNetstatProvisioner(),
+	})
+
+	driver := &reconcilingDriver{
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+		},
+	}
+	host := &Host{
+		Driver: driver,
+		HostOptions: &Options{
+			SwarmOptions:  &swarm.Options{},
+			AuthOptions:   &auth.Options{},
+			EngineOptions: &engine.Options{},
+		},
+	}
+
+	if err := host.Provision(); err != nil {
+		t.Fatalf("Expected no error but got one: %s", err)
+	}
+	if !driver.reconciled {
+		t.Fatal("Expected the driver to be reconciled before provisioning")
+	}
+}
@@ -148,6 +148,13 @@ func (api *Client) performCreate(h *host.Host) error {
 		return fmt.Errorf("Error in driver during machine creation: %s", err)
 	}
 
+	// The machine exists by now, failing would leave it behind
+	engineFlags, err := drivers.GetEngineFlags(h.Driver)
+	if err != nil {
+		log.Warnf("Error getting engine flags from driver, the Docker daemon is configured with the given flags only: %s", err)
+	}
+	h.HostOptions.EngineOptions.ArbitraryFlags = drivers.MergeEngineFlags(h.HostOptions.EngineOptions.ArbitraryFlags, engineFlags)
+
 	if err := api.Save(h); err != nil {
 		return fmt.Errorf("Error saving host to store after attempting creation: %s", err)
 	}
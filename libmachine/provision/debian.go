@@ -123,16 +123,20 @@ func (provisioner *DebianProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
-	log.Debug("installing base packages")
-	for _, pkg := range provisioner.Packages {
-		if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
-			return err
+	if skipDockerInstall(provisioner, engineOptions.InstallURL) {
+		log.Info("Docker is already installed, skipping installation")
+	} else {
+		log.Debug("installing base packages")
+		for _, pkg := range provisioner.Packages {
+			if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
+				return err
+			}
 		}
-	}
 
-	log.Debug("installing docker")
-	if err := installDockerGeneric(provisioner, engineOptions.InstallURL); err != nil {
-		return err
+		log.Debug("installing docker")
+		if err := installDockerGeneric(provisioner, engineOptions.InstallURL); err != nil {
+			return err
+		}
 	}
 
 	log.Debug("waiting for docker daemon")
@@ -169,21 +169,25 @@ func (provisioner *RedHatProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
-	for _, pkg := range provisioner.Packages {
-		log.Debugf("installing base package: name=%s", pkg)
-		if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
-			return err
+	if skipDockerInstall(provisioner, engineOptions.InstallURL) {
+		log.Info("Docker is already installed, skipping installation")
+	} else {
+		for _, pkg := range provisioner.Packages {
+			log.Debugf("installing base package: name=%s", pkg)
+			if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
+				return err
+			}
 		}
-	}
 
-	// update OS -- this is needed for libdevicemapper and the docker install
-	if _, err := provisioner.SSHCommand("sudo yum -y update"); err != nil {
-		return err
-	}
+		// update OS -- this is needed for libdevicemapper and the docker install
+		if _, err := provisioner.SSHCommand("sudo yum -y update"); err != nil {
+			return err
+		}
 
-	// install docker
-	if err := installDocker(provisioner); err != nil {
-		return err
+		// install docker
+		if err := installDocker(provisioner); err != nil {
+			return err
+		}
 	}
 
 	if err := mcnutils.WaitFor(provisioner.dockerDaemonResponding); err != nil {
@@ -151,15 +151,19 @@ func (provisioner *UbuntuProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
-	for _, pkg := range provisioner.Packages {
-		if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
-			return err
+	if skipDockerInstall(provisioner, engineOptions.InstallURL) {
+		log.Info("Docker is already installed, skipping installation")
+	} else {
+		for _, pkg := range provisioner.Packages {
+			if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
+				return err
+			}
 		}
-	}
 
-	log.Info("Installing Docker...")
-	if err := installDockerGeneric(provisioner, engineOptions.InstallURL); err != nil {
-		return err
+		log.Info("Installing Docker...")
+		if err := installDockerGeneric(provisioner, engineOptions.InstallURL); err != nil {
+			return err
+		}
 	}
 
 	if err := mcnutils.WaitFor(provisioner.dockerDaemonResponding); err != nil {
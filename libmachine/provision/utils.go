@@ -13,6 +13,7 @@ import (
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
@@ -24,6 +25,23 @@ type DockerOptions struct {
 	EngineOptionsPath string
 }
 
+// dockerInstalled returns true if Docker is installed already
+func dockerInstalled(p SSHCommander) bool {
+	_, err := p.SSHCommand("type docker")
+	return err == nil
+}
+
+// skipDockerInstall returns true if the driver reports the machine is created
+// from the image of a provisioned machine and Docker is found on it, so the
+// packages and Docker are not installed again. Docker is always installed
+// from an install URL other than the default one.
+func skipDockerInstall(p Provisioner, installURL string) bool {
+	if installURL != "" && installURL != drivers.DefaultEngineInstallURL {
+		return false
+	}
+	return drivers.EnginePreinstalled(p.GetDriver()) && dockerInstalled(p)
+}
+
 func installDockerGeneric(p Provisioner, baseURL string) error {
 	// install docker - until cloudinit we use ubuntu everywhere so we
 	// just install it using the docker repos
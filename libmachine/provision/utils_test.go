@@ -8,6 +8,7 @@ import (
 
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/provisiontest"
@@ -225,3 +226,49 @@ func TestGetFilesystemType(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "btrfs", fsType)
 }
+
+func TestDockerInstalled(t *testing.T) {
+	sshCmder := &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{},
+	}
+	assert.False(t, dockerInstalled(sshCmder))
+
+	sshCmder.Responses["type docker"] = "docker is /usr/bin/docker\n"
+	assert.True(t, dockerInstalled(sshCmder))
+}
+
+type fakeEngineImageDriver struct {
+	fakedriver.Driver
+}
+
+func (d *fakeEngineImageDriver) EnginePreinstalled() bool {
+	return true
+}
+
+func TestSkipDockerInstall(t *testing.T) {
+	dockerFound := map[string]string{"type docker": "docker is /usr/bin/docker\n"}
+
+	var tests = []struct {
+		description string
+		driver      drivers.Driver
+		installURL  string
+		responses   map[string]string
+		expected    bool
+	}{
+		{"driver not opting in", &fakedriver.Driver{}, drivers.DefaultEngineInstallURL, dockerFound, false},
+		{"custom install URL", &fakeEngineImageDriver{}, "https://test.docker.com", dockerFound, false},
+		{"docker found", &fakeEngineImageDriver{}, drivers.DefaultEngineInstallURL, dockerFound, true},
+		{"no install URL", &fakeEngineImageDriver{}, "", dockerFound, true},
+		{"docker check failing", &fakeEngineImageDriver{}, drivers.DefaultEngineInstallURL, map[string]string{}, false},
+	}
+
+	for _, test := range tests {
+		p := &fakeProvisioner{GenericProvisioner{
+			Driver: test.driver,
+		}}
+		p.SSHCommander = &provisiontest.FakeSSHCommander{
+			Responses: test.responses,
+		}
+		assert.Equal(t, test.expected, skipDockerInstall(p, test.installURL), test.description)
+	}
+}
@@ -0,0 +1,235 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const defaultBastionPort = 22
+
+// Bastion is the jump host to reach the machines which are only accessible in
+// a private network. The bastion is authenticated with the keys of ssh-agent
+// and the default identities of the user.
+type Bastion struct {
+	User string
+	Host string
+	Port int
+}
+
+// ParseBastion parses the bastion in the form user@host[:port]
+func ParseBastion(spec string) (*Bastion, error) {
+	at := strings.LastIndex(spec, "@")
+	if at <= 0 || at == len(spec)-1 {
+		return nil, fmt.Errorf("invalid bastion %q, expected user@host[:port]", spec)
+	}
+
+	bastion := &Bastion{
+		User: spec[:at],
+		Host: spec[at+1:],
+		Port: defaultBastionPort,
+	}
+
+	if host, port, err := net.SplitHostPort(bastion.Host); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %q of bastion %q", port, spec)
+		}
+		bastion.Host = host
+		bastion.Port = p
+	}
+
+	if bastion.Host == "" || strings.ContainsAny(bastion.Host, ":[] ") {
+		return nil, fmt.Errorf("invalid host of bastion %q", spec)
+	}
+
+	return bastion, nil
+}
+
+func (b *Bastion) String() string {
+	return fmt.Sprintf("%s@%s", b.User, b.Address())
+}
+
+// Address returns the host:port of the bastion
+func (b *Bastion) Address() string {
+	return net.JoinHostPort(b.Host, strconv.Itoa(b.Port))
+}
+
+// proxyCommand returns the ProxyCommand of the external ssh binary to connect
+// through the bastion
+func (b *Bastion) proxyCommand(sshBinaryPath string) string {
+	if strings.Contains(sshBinaryPath, " ") {
+		sshBinaryPath = `"` + sshBinaryPath + `"`
+	}
+	args := append([]string{sshBinaryPath}, baseSSHArgs...)
+	args = append(args, "-W", "%h:%p", "-p", strconv.Itoa(b.Port), fmt.Sprintf("%s@%s", b.User, b.Host))
+	return strings.Join(args, " ")
+}
+
+// NewBastionConfig returns the config of native Go SSH to authenticate with
+// the bastion, the machine keys in auth are tried after ssh-agent and the
+// default identities
+func NewBastionConfig(user string, auth *Auth) ssh.ClientConfig {
+	var authMethods []ssh.AuthMethod
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			authMethods = append(authMethods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		} else {
+			log.Debugf("Error connecting to ssh-agent: %s", err)
+		}
+	}
+
+	var signers []ssh.Signer
+	keys := []string{}
+	for _, name := range []string{"id_rsa", "id_ecdsa", "id_dsa"} {
+		keys = append(keys, filepath.Join(mcnutils.GetHomeDir(), ".ssh", name))
+	}
+	for _, k := range append(keys, auth.Keys...) {
+		key, err := ioutil.ReadFile(k)
+		if err != nil {
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			// The passphrase protected keys are only used with ssh-agent
+			log.Debugf("Skipping SSH key %s for bastion: %s", k, err)
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		authMethods = append(authMethods, ssh.PublicKeys(signers...))
+	}
+
+	return ssh.ClientConfig{
+		User: user,
+		Auth: authMethods,
+	}
+}
+
+// dialBastion connects to the bastion with native Go SSH
+func dialBastion(bastion *Bastion, config *ssh.ClientConfig) (*ssh.Client, error) {
+	client, err := ssh.Dial("tcp", bastion.Address(), config)
+	if err != nil {
+		return nil, fmt.Errorf("Error dialing bastion %s: %s", bastion, err)
+	}
+	return client, nil
+}
+
+// dialThroughBastion connects to the SSH server at addr through the bastion,
+// the connection to the bastion is closed with the returned client
+func dialThroughBastion(bastion *Bastion, bastionConfig *ssh.ClientConfig, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	bastionClient, err := dialBastion(bastion, bastionConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := bastionClient.Dial("tcp", addr)
+	if err != nil {
+		bastionClient.Close()
+		return nil, fmt.Errorf("Error dialing %s through bastion %s: %s", addr, bastion, err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		bastionClient.Close()
+		return nil, err
+	}
+
+	client := ssh.NewClient(c, chans, reqs)
+	go func() {
+		client.Wait()
+		bastionClient.Close()
+	}()
+	return client, nil
+}
+
+// Tunnel forwards the connections to a local address to a remote address
+// through the bastion, e.g. to reach the Docker daemon in a private network.
+type Tunnel struct {
+	listener   net.Listener
+	client     *ssh.Client
+	remoteAddr string
+	wg         sync.WaitGroup
+}
+
+// NewTunnel listens on localAddr, e.g. localhost:0 for a random port, and
+// forwards the accepted connections to remoteAddr through the bastion
+func NewTunnel(bastion *Bastion, auth *Auth, localAddr, remoteAddr string) (*Tunnel, error) {
+	config := NewBastionConfig(bastion.User, auth)
+	client, err := dialBastion(bastion, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	t := &Tunnel{
+		listener:   listener,
+		client:     client,
+		remoteAddr: remoteAddr,
+	}
+	t.wg.Add(1)
+	go t.serve()
+	return t, nil
+}
+
+// Port returns the local port the tunnel listens on
+func (t *Tunnel) Port() int {
+	return t.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops forwarding and closes the connection to the bastion
+func (t *Tunnel) Close() error {
+	err := t.listener.Close()
+	t.wg.Wait()
+	t.client.Close()
+	return err
+}
+
+func (t *Tunnel) serve() {
+	defer t.wg.Done()
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go t.forward(local)
+	}
+}
+
+func (t *Tunnel) forward(local net.Conn) {
+	defer local.Close()
+
+	remote, err := t.client.Dial("tcp", t.remoteAddr)
+	if err != nil {
+		log.Debugf("Error forwarding to %s: %s", t.remoteAddr, err)
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	pipe := func(dst io.Writer, src io.Reader) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go pipe(remote, local)
+	go pipe(local, remote)
+	<-done
+}
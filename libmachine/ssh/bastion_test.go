@@ -0,0 +1,151 @@
+package ssh
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseBastion(t *testing.T) {
+	cases := []struct {
+		spec     string
+		expected *Bastion
+	}{
+		{"admin@jump.example.com:2222", &Bastion{User: "admin", Host: "jump.example.com", Port: 2222}},
+		{"admin@203.0.113.10", &Bastion{User: "admin", Host: "203.0.113.10", Port: 22}},
+		{"jump.example.com", nil},
+		{"@jump.example.com", nil},
+		{"admin@", nil},
+		{"admin@jump.example.com:ssh", nil},
+		{"admin@jump.example.com:70000", nil},
+	}
+
+	for _, c := range cases {
+		bastion, err := ParseBastion(c.spec)
+		if c.expected == nil {
+			assert.Error(t, err, c.spec)
+			continue
+		}
+		assert.NoError(t, err, c.spec)
+		assert.Equal(t, c.expected, bastion, c.spec)
+	}
+}
+
+func TestBastionProxyCommand(t *testing.T) {
+	bastion := &Bastion{User: "admin", Host: "jump.example.com", Port: 2222}
+	command := bastion.proxyCommand("/usr/bin/ssh")
+	assert.Contains(t, command, "/usr/bin/ssh -o BatchMode=yes")
+	assert.Contains(t, command, "-W %h:%p -p 2222 admin@jump.example.com")
+	assert.Contains(t, bastion.proxyCommand(`C:\Program Files\Git\bin\ssh.exe`), `"C:\Program Files\Git\bin\ssh.exe" -o`)
+}
+
+// fakeBastion accepts any client and forwards its direct-tcpip channels
+func fakeBastion(t *testing.T) *Bastion {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					var target struct {
+						Host     string
+						Port     uint32
+						OrigHost string
+						OrigPort uint32
+					}
+					if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+						newChannel.Reject(ssh.ConnectionFailed, err.Error())
+						continue
+					}
+					remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+					if err != nil {
+						newChannel.Reject(ssh.ConnectionFailed, err.Error())
+						continue
+					}
+					channel, requests, _ := newChannel.Accept()
+					go ssh.DiscardRequests(requests)
+					go func() {
+						io.Copy(channel, remote)
+						channel.Close()
+					}()
+					go func() {
+						io.Copy(remote, channel)
+						remote.Close()
+					}()
+				}
+			}()
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	bastion, err := ParseBastion("admin@127.0.0.1:" + strconv.Itoa(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bastion
+}
+
+func TestTunnel(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn)
+		}
+	}()
+
+	tunnel, err := NewTunnel(fakeBastion(t), &Auth{}, "127.0.0.1:0", echo.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tunnel.Close()
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(tunnel.Port())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("ping"))
+	assert.NoError(t, err)
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+}
@@ -25,9 +25,11 @@ type ExternalClient struct {
 }
 
 type NativeClient struct {
-	Config   ssh.ClientConfig
-	Hostname string
-	Port     int
+	Config        ssh.ClientConfig
+	Hostname      string
+	Port          int
+	Bastion       *Bastion
+	BastionConfig ssh.ClientConfig
 }
 
 type Auth struct {
@@ -74,27 +76,48 @@ func SetDefaultClient(clientType ClientType) {
 }
 
 func NewClient(user string, host string, port int, auth *Auth) (Client, error) {
+	return NewBastionClient(user, host, port, auth, nil)
+}
+
+// NewBastionClient returns the client connecting through the bastion, or
+// connecting directly if bastion is nil
+func NewBastionClient(user string, host string, port int, auth *Auth, bastion *Bastion) (Client, error) {
 	sshBinaryPath, err := exec.LookPath("ssh")
 	if err != nil {
 		log.Debug("SSH binary not found, using native Go implementation")
-		client, err := NewNativeClient(user, host, port, auth)
+		client, err := newNativeBastionClient(user, host, port, auth, bastion)
 		log.Debug(client)
 		return client, err
 	}
 
 	if defaultClientType == Native {
 		log.Debug("Using SSH client type: native")
-		client, err := NewNativeClient(user, host, port, auth)
+		client, err := newNativeBastionClient(user, host, port, auth, bastion)
 		log.Debug(client)
 		return client, err
 	}
 
 	log.Debug("Using SSH client type: external")
 	client, err := NewExternalClient(sshBinaryPath, user, host, port, auth)
+	if err == nil && bastion != nil {
+		client.BaseArgs = append(client.BaseArgs, "-o", "ProxyCommand="+bastion.proxyCommand(sshBinaryPath))
+	}
 	log.Debug(client)
 	return client, err
 }
 
+func newNativeBastionClient(user, host string, port int, auth *Auth, bastion *Bastion) (Client, error) {
+	client, err := NewNativeClient(user, host, port, auth)
+	if err != nil || bastion == nil {
+		return client, err
+	}
+
+	nativeClient := client.(NativeClient)
+	nativeClient.Bastion = bastion
+	nativeClient.BastionConfig = NewBastionConfig(bastion.User, auth)
+	return nativeClient, nil
+}
+
 func NewNativeClient(user, host string, port int, auth *Auth) (Client, error) {
 	config, err := NewNativeConfig(user, auth)
 	if err != nil {
@@ -137,11 +160,21 @@ func NewNativeConfig(user string, auth *Auth) (ssh.ClientConfig, error) {
 	}, nil
 }
 
+func (client NativeClient) dial() (*ssh.Client, error) {
+	addr := fmt.Sprintf("%s:%d", client.Hostname, client.Port)
+	if client.Bastion != nil {
+		return dialThroughBastion(client.Bastion, &client.BastionConfig, addr, &client.Config)
+	}
+	return ssh.Dial("tcp", addr, &client.Config)
+}
+
 func (client NativeClient) dialSuccess() bool {
-	if _, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config); err != nil {
+	conn, err := client.dial()
+	if err != nil {
 		log.Debugf("Error dialing TCP: %s", err)
 		return false
 	}
+	conn.Close()
 	return true
 }
 
@@ -150,7 +183,7 @@ func (client NativeClient) session(command string) (*ssh.Session, error) {
 		return nil, fmt.Errorf("Error attempting SSH client dial: %s", err)
 	}
 
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config)
+	conn, err := client.dial()
 	if err != nil {
 		return nil, fmt.Errorf("Mysterious error dialing TCP for SSH (we already succeeded at least once) : %s", err)
 	}
@@ -205,7 +238,7 @@ func (client NativeClient) Shell(args ...string) error {
 	var (
 		termWidth, termHeight int
 	)
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config)
+	conn, err := client.dial()
 	if err != nil {
 		return err
 	}
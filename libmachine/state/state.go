@@ -13,6 +13,9 @@ const (
 	Starting
 	Error
 	Timeout
+	// Reclaimed is the state of a preemptible instance taken back by the
+	// provider, which can not be started again
+	Reclaimed
 )
 
 var states = []string{
@@ -25,6 +28,7 @@ var states = []string{
 	"Starting",
 	"Error",
 	"Timeout",
+	"Reclaimed",
 }
 
 // Given a State type, returns its string representation
@@ -14,4 +14,7 @@ func TestDaemonCreate(t *testing.T) {
 	if Error.String() != "Error" {
 		t.Fatal("Error state should be 'Error'")
 	}
+	if Reclaimed.String() != "Reclaimed" {
+		t.Fatal("Reclaimed state should be 'Reclaimed'")
+	}
 }